@@ -0,0 +1,614 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"sort"
+	"strings"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+// DefaultTagKey is the struct tag key used to customize a field, for example,
+// `avro:"user_name,default=\"guest\",aliases=name|login"`. The default value is a JSON value.
+const DefaultTagKey = "avro"
+
+type Options struct {
+	// Packages, if set, is used to find the constants declared with a named type, which are
+	// emitted as enums.
+	Packages *parser.PackageSet
+
+	// TagKey is the struct tag key that contains the field overrides, defaults to DefaultTagKey
+	TagKey string
+
+	// Namespace returns the namespace of the types of a Go package. It defaults to the import path
+	// with the separators replaced by dots, for example, github.com.acme.app.events.
+	Namespace func(importPath string) string
+}
+
+// Record is an Avro record schema
+type Record struct {
+	Type      string   `json:"type"`
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	Doc       string   `json:"doc,omitempty"`
+	Aliases   []string `json:"aliases,omitempty"`
+	Fields    []*Field `json:"fields"`
+}
+
+// Field is a field of a record
+type Field struct {
+	Name    string          `json:"name"`
+	Type    interface{}     `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+	Doc     string          `json:"doc,omitempty"`
+	Aliases []string        `json:"aliases,omitempty"`
+}
+
+// Enum is an Avro enum schema
+type Enum struct {
+	Type      string   `json:"type"`
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	Doc       string   `json:"doc,omitempty"`
+	Symbols   []string `json:"symbols"`
+}
+
+// Array is an Avro array schema
+type Array struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+// Map is an Avro map schema, keys are always strings
+type Map struct {
+	Type   string      `json:"type"`
+	Values interface{} `json:"values"`
+}
+
+// Fixed is an Avro fixed schema, a sequence of bytes of the given size
+type Fixed struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Size      int64  `json:"size"`
+}
+
+// LogicalType is a primitive type annotated with a logical type, like timestamp-millis
+type LogicalType struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+// Union is a list of alternative schemas
+type Union []interface{}
+
+type generator struct {
+	opts      Options
+	namespace string // Namespace of the root record, used by anonymous fixed types
+	defined   map[string]struct{}
+	visiting  map[string]struct{}
+}
+
+// -----------------------------------------------------------------------------
+
+var wellKnownTypes = map[string]func() interface{}{
+	"time.Time": func() interface{} {
+		return &LogicalType{
+			Type:        "long",
+			LogicalType: "timestamp-millis",
+		}
+	},
+	"time.Duration": func() interface{} {
+		return "long"
+	},
+	"github.com/google/uuid.UUID": func() interface{} {
+		return &LogicalType{
+			Type:        "string",
+			LogicalType: "uuid",
+		}
+	},
+}
+
+// Generate returns the Avro record schema of a struct declaration. Named types used by the fields
+// are defined the first time they appear and referenced by name afterwards. References must be
+// resolved before calling this function.
+func Generate(pd *parser.ParsedDeclaration, opts Options) (*Record, error) {
+	if len(opts.TagKey) == 0 {
+		opts.TagKey = DefaultTagKey
+	}
+	if opts.Namespace == nil {
+		opts.Namespace = defaultNamespace
+	}
+	if len(pd.TypeParams) > 0 {
+		return nil, fmt.Errorf("generic declaration %s must be instantiated", pd.Name)
+	}
+	if !parser.IsStruct(pd.Underlying()) {
+		return nil, fmt.Errorf("declaration %s is not a struct", pd.Name)
+	}
+
+	g := generator{
+		opts:     opts,
+		defined:  make(map[string]struct{}),
+		visiting: make(map[string]struct{}),
+	}
+	if pf := pd.File(); pf != nil {
+		g.namespace = opts.Namespace(pf.Module.FullName())
+	}
+
+	s, err := g.named(pd, pd.Name, &parser.ParsedNonNativeType{
+		Name: pd.Name,
+		Ref:  pd,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Done
+	return s.(*Record), nil
+}
+
+// JSON returns the schema encoded as indented JSON, the content of an .avsc file
+func (r *Record) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// -----------------------------------------------------------------------------
+
+func (g *generator) schema(t parser.ParsedType) (interface{}, error) {
+	switch tType := t.(type) {
+	case *parser.ParsedNativeType:
+		return primitiveType(tType.Name)
+
+	case *parser.ParsedNonNativeType:
+		if fn, ok := wellKnownTypes[codegen.ID(tType)]; ok {
+			return fn(), nil
+		}
+		if tType.Ref == nil {
+			return nil, fmt.Errorf("unresolved reference to %s", tType.Name)
+		}
+
+		pd := tType.Ref
+		if pd.IsAlias {
+			return g.schema(pd.Type)
+		}
+		if len(pd.TypeParams) > 0 {
+			return nil, fmt.Errorf("generic type %s must be instantiated", pd.Name)
+		}
+		return g.named(pd, pd.Name, tType)
+
+	case *parser.ParsedIndex:
+		pd, err := parser.Instantiate(tType)
+		if err != nil {
+			return nil, fmt.Errorf("unable to instantiate %s [err=%v]", parser.Render(tType, nil), err)
+		}
+		if generic, ok := tType.Type.(*parser.ParsedNonNativeType); ok && generic.Ref != nil {
+			pd.Doc = generic.Ref.Doc
+		}
+		return g.named(pd, codegen.TypeName(pd.Name), tType)
+
+	case *parser.ParsedPointer:
+		s, err := g.schema(tType.ToType)
+		if err != nil {
+			return nil, err
+		}
+		if union, ok := s.(Union); ok {
+			for _, item := range union {
+				if item == "null" {
+					return union, nil // Already nullable, like **T
+				}
+			}
+			return append(Union{"null"}, union...), nil
+		}
+		return Union{"null", s}, nil
+
+	case *parser.ParsedArray:
+		if codegen.IsByte(tType.ValueType) {
+			if len(tType.Size) > 0 {
+				return g.fixed("", g.namespace, tType)
+			}
+			return "bytes", nil
+		}
+		items, err := g.schema(tType.ValueType)
+		if err != nil {
+			return nil, err
+		}
+		return &Array{
+			Type:  "array",
+			Items: items,
+		}, nil
+
+	case *parser.ParsedMap:
+		if !parser.IsString(tType.KeyType) {
+			return nil, fmt.Errorf("map keys must be strings")
+		}
+		values, err := g.schema(tType.ValueType)
+		if err != nil {
+			return nil, err
+		}
+		return &Map{
+			Type:   "map",
+			Values: values,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("type %s cannot be represented in Avro", parser.Render(t, nil))
+}
+
+// named returns the record or enum schema of a declaration the first time it is used and its full
+// name afterwards. Other named types are represented by the schema of their underlying type.
+func (g *generator) named(pd *parser.ParsedDeclaration, name string, ref parser.ParsedType) (interface{}, error) {
+	namespace := ""
+	if pf := pd.File(); pf != nil {
+		namespace = g.opts.Namespace(pf.Module.FullName())
+	}
+	fullName := name
+	if len(namespace) > 0 {
+		fullName = namespace + "." + name
+	}
+	if _, ok := g.defined[fullName]; ok {
+		return fullName, nil
+	}
+
+	if g.opts.Packages != nil && len(pd.TypeParams) == 0 {
+		if symbols := enumSymbols(g.opts.Packages.EnumValues(pd)); len(symbols) > 0 {
+			g.defined[fullName] = struct{}{}
+			return &Enum{
+				Type:      "enum",
+				Name:      name,
+				Namespace: namespace,
+				Doc:       pd.Doc,
+				Symbols:   symbols,
+			}, nil
+		}
+	}
+
+	ps, ok := parser.Underlying(ref).(*parser.ParsedStruct)
+	if !ok {
+		underlying := parser.Underlying(ref)
+		if underlying == nil {
+			return nil, fmt.Errorf("invalid recursive type %s", pd.Name)
+		}
+		if pa, isArray := underlying.(*parser.ParsedArray); isArray && len(pa.Size) > 0 && codegen.IsByte(pa.ValueType) {
+			return g.fixed(name, namespace, pa)
+		}
+
+		// Only records are referenced by name, so types like `type Tree map[string]Tree` cannot be
+		// represented
+		if _, visiting := g.visiting[fullName]; visiting {
+			return nil, fmt.Errorf("invalid recursive type %s", pd.Name)
+		}
+		g.visiting[fullName] = struct{}{}
+		defer delete(g.visiting, fullName)
+
+		return g.schema(underlying)
+	}
+
+	// Register the record before processing the fields so recursive references use the name
+	g.defined[fullName] = struct{}{}
+	r := &Record{
+		Type:      "record",
+		Name:      name,
+		Namespace: namespace,
+		Doc:       pd.Doc,
+		Fields:    make([]*Field, 0),
+	}
+	for _, jf := range ps.JSONFields() {
+		f, err := g.field(jf)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert field %s of %s [err=%v]", jf.Name, name, err)
+		}
+		if f != nil {
+			r.Fields = append(r.Fields, f)
+		}
+	}
+
+	// Done
+	return r, nil
+}
+
+// fixed returns the fixed schema of a byte array the first time it is used and its full name
+// afterwards. Anonymous arrays are named after their size, like Fixed16.
+func (g *generator) fixed(name string, namespace string, pa *parser.ParsedArray) (interface{}, error) {
+	if pa.ParsedInt == nil {
+		return nil, fmt.Errorf("array length %s must be a constant", pa.Size)
+	}
+	if len(name) == 0 {
+		name = fmt.Sprintf("Fixed%d", *pa.ParsedInt)
+	}
+	fullName := name
+	if len(namespace) > 0 {
+		fullName = namespace + "." + name
+	}
+	if _, ok := g.defined[fullName]; ok {
+		return fullName, nil
+	}
+
+	g.defined[fullName] = struct{}{}
+	return &Fixed{
+		Type:      "fixed",
+		Name:      name,
+		Namespace: namespace,
+		Size:      *pa.ParsedInt,
+	}, nil
+}
+
+func (g *generator) field(jf parser.JSONField) (*Field, error) {
+	s, err := g.schema(jf.Field.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Field{
+		Name: jf.Name,
+		Type: s,
+		Doc:  jf.Field.Doc,
+	}
+	if union, ok := s.(Union); ok && len(union) > 0 && union[0] == "null" {
+		f.Default = json.RawMessage("null")
+	}
+
+	tag, ok := jf.Field.Tags.GetTag(g.opts.TagKey)
+	if !ok {
+		if !isValidName(f.Name) {
+			return nil, fmt.Errorf("invalid Avro name %s, set a valid one in the %s tag", f.Name, g.opts.TagKey)
+		}
+		return f, nil
+	}
+	if tag == "-" {
+		return nil, nil
+	}
+
+	name, props := codegen.SplitTag(tag)
+	if len(name) > 0 {
+		f.Name = name
+	}
+	if !isValidName(f.Name) {
+		return nil, fmt.Errorf("invalid Avro name %s, set a valid one in the %s tag", f.Name, g.opts.TagKey)
+	}
+	if value, found := props.GetProperty("default"); found {
+		f.Default, err = defaultValue(value, s)
+		if err != nil {
+			return nil, err
+		}
+		// The default value of a union must match its first type
+		if union, isUnion := s.(Union); isUnion && len(union) == 2 && union[0] == "null" && value != "null" {
+			f.Type = Union{union[1], "null"}
+		}
+	}
+	if aliases, found := props.GetProperty("aliases"); found && len(aliases) > 0 {
+		f.Aliases = strings.Split(aliases, "|")
+	}
+
+	// Done
+	return f, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func primitiveType(name string) (interface{}, error) {
+	switch name {
+	case "bool":
+		return "boolean", nil
+	case "string":
+		return "string", nil
+	case "int8", "int16", "int32", "rune", "uint8", "uint16", "byte":
+		return "int", nil
+	case "int", "int64", "uint", "uint32", "uint64", "uintptr":
+		return "long", nil
+	case "float32":
+		return "float", nil
+	case "float64":
+		return "double", nil
+	}
+	return nil, fmt.Errorf("type %s cannot be represented in Avro", name)
+}
+
+// enumSymbols returns the symbols of an enum. The values of string constants are used if all of
+// them are valid and different Avro names, else the names of the constants are used. Constants of
+// integer enums are sorted by value, so the position of each symbol follows the order of the Go
+// values, and constants with the value of a previous one are skipped.
+func enumSymbols(constants []*parser.ParsedConstant) []string {
+	constants = append(make([]*parser.ParsedConstant, 0, len(constants)), constants...)
+	integer := isIntegerEnum(constants)
+	if integer {
+		sort.SliceStable(constants, func(i, j int) bool {
+			return constant.Compare(constants[i].ParsedValue, token.LSS, constants[j].ParsedValue)
+		})
+	}
+
+	names := make([]string, 0, len(constants))
+	values := make([]string, 0, len(constants))
+	used := make(map[string]struct{})
+	for idx, pc := range constants {
+		if pc.Name == "_" {
+			continue
+		}
+		if integer && idx > 0 && constant.Compare(pc.ParsedValue, token.EQL, constants[idx-1].ParsedValue) {
+			continue
+		}
+		names = append(names, pc.Name)
+		if values == nil || pc.ParsedValue == nil || pc.ParsedValue.Kind() != constant.String {
+			values = nil
+			continue
+		}
+		value := constant.StringVal(pc.ParsedValue)
+		if _, ok := used[value]; ok || (!isValidName(value)) {
+			values = nil
+			continue
+		}
+		used[value] = struct{}{}
+		values = append(values, value)
+	}
+	if values != nil && len(values) == len(names) {
+		return values
+	}
+	return names
+}
+
+func isIntegerEnum(constants []*parser.ParsedConstant) bool {
+	for _, pc := range constants {
+		if pc.ParsedValue == nil || pc.ParsedValue.Kind() != constant.Int {
+			return false
+		}
+	}
+	return len(constants) > 0
+}
+
+// defaultValue parses the default value given in a tag and checks it matches the schema. Values
+// that are not valid JSON are used as strings if the field is a string.
+func defaultValue(value string, s interface{}) (json.RawMessage, error) {
+	if union, ok := s.(Union); ok && len(union) == 2 && union[0] == "null" && value != "null" {
+		s = union[1]
+	}
+
+	data := []byte(value)
+	if !json.Valid(data) {
+		if !isString(s) {
+			return nil, fmt.Errorf("invalid default value %s", value)
+		}
+		data, _ = json.Marshal(value)
+	}
+
+	var v interface{}
+
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	_ = dec.Decode(&v)
+	if !matchesSchema(v, s) {
+		return nil, fmt.Errorf("default value %s does not match the field type", value)
+	}
+
+	// Done
+	return data, nil
+}
+
+// matchesSchema checks if a JSON value is a valid default value of a schema. Named types defined
+// before are referenced by name and not checked.
+func matchesSchema(v interface{}, s interface{}) bool {
+	switch sType := s.(type) {
+	case string:
+		switch sType {
+		case "null":
+			return v == nil
+		case "boolean":
+			_, ok := v.(bool)
+			return ok
+		case "int", "long":
+			n, ok := v.(json.Number)
+			if ok {
+				_, err := n.Int64()
+				ok = err == nil
+			}
+			return ok
+		case "float", "double":
+			_, ok := v.(json.Number)
+			return ok
+		case "string", "bytes":
+			_, ok := v.(string)
+			return ok
+		}
+		return true
+
+	case *LogicalType:
+		return matchesSchema(v, sType.Type)
+
+	case *Fixed:
+		_, ok := v.(string)
+		return ok
+
+	case *Enum:
+		symbol, ok := v.(string)
+		if ok {
+			ok = false
+			for _, item := range sType.Symbols {
+				if item == symbol {
+					ok = true
+					break
+				}
+			}
+		}
+		return ok
+
+	case *Array:
+		items, ok := v.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if !matchesSchema(item, sType.Items) {
+				return false
+			}
+		}
+		return true
+
+	case *Map:
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for _, value := range values {
+			if !matchesSchema(value, sType.Values) {
+				return false
+			}
+		}
+		return true
+
+	case *Record:
+		_, ok := v.(map[string]interface{})
+		return ok
+
+	case Union:
+		// The default value of a union must match its first type
+		return len(sType) > 0 && matchesSchema(v, sType[0])
+	}
+	return false
+}
+
+func isString(s interface{}) bool {
+	if lt, ok := s.(*LogicalType); ok {
+		s = lt.Type
+	}
+	return s == "string"
+}
+
+// defaultNamespace converts an import path to a namespace, for example, github.com/acme/app-events
+// becomes github.com.acme.app_events
+func defaultNamespace(importPath string) string {
+	parts := strings.FieldsFunc(importPath, func(r rune) bool {
+		return r == '/' || r == '.'
+	})
+	for idx, part := range parts {
+		part = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+				return r
+			}
+			return '_'
+		}, part)
+		if part[0] >= '0' && part[0] <= '9' {
+			part = "_" + part
+		}
+		parts[idx] = part
+	}
+	return strings.Join(parts, ".")
+}
+
+func isValidName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for idx, ch := range name {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch == '_':
+		case ch >= '0' && ch <= '9' && idx > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
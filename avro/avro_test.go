@@ -0,0 +1,419 @@
+package avro_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/avro"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package events
+
+import (
+	"time"
+)
+
+type Kind string
+
+const (
+	Created Kind = "created"
+	Deleted Kind = "deleted"
+)
+
+// Event is stored in the data lake
+type Event struct {
+	ID       string            ` + "`json:\"id\"`" + `
+	Kind     Kind              ` + "`json:\"kind\"`" + `
+	At       time.Time         ` + "`json:\"at\"`" + `
+	User     *User             ` + "`json:\"user\"`" + `
+	Tags     []string          ` + "`json:\"tags\"`" + `
+	Extra    map[string]int32  ` + "`json:\"extra\"`" + `
+	Source   *string           ` + "`json:\"source\" avro:\"origin,default=web,aliases=src|from\"`" + `
+	Previous *Event            ` + "`json:\"previous\"`" + `
+	Internal string            ` + "`avro:\"-\"`" + `
+}
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + ` // Display name
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	r, err := avro.Generate(ps.Lookup("github.com/mxmauro/gofile-parser-test", "Event"), avro.Options{
+		Packages: ps,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	data, err := r.JSON()
+	if err != nil {
+		t.Fatalf("unable to encode schema [err=%v]", err)
+	}
+	expected := `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "github.com.mxmauro.gofile_parser_test",
+  "doc": "Event is stored in the data lake",
+  "fields": [
+    {
+      "name": "id",
+      "type": "string"
+    },
+    {
+      "name": "kind",
+      "type": {
+        "type": "enum",
+        "name": "Kind",
+        "namespace": "github.com.mxmauro.gofile_parser_test",
+        "symbols": [
+          "created",
+          "deleted"
+        ]
+      }
+    },
+    {
+      "name": "at",
+      "type": {
+        "type": "long",
+        "logicalType": "timestamp-millis"
+      }
+    },
+    {
+      "name": "user",
+      "type": [
+        "null",
+        {
+          "type": "record",
+          "name": "User",
+          "namespace": "github.com.mxmauro.gofile_parser_test",
+          "fields": [
+            {
+              "name": "name",
+              "type": "string",
+              "doc": "Display name"
+            }
+          ]
+        }
+      ],
+      "default": null
+    },
+    {
+      "name": "tags",
+      "type": {
+        "type": "array",
+        "items": "string"
+      }
+    },
+    {
+      "name": "extra",
+      "type": {
+        "type": "map",
+        "values": "int"
+      }
+    },
+    {
+      "name": "origin",
+      "type": [
+        "string",
+        "null"
+      ],
+      "default": "web",
+      "aliases": [
+        "src",
+        "from"
+      ]
+    },
+    {
+      "name": "previous",
+      "type": [
+        "null",
+        "github.com.mxmauro.gofile_parser_test.Event"
+      ],
+      "default": null
+    }
+  ]
+}`
+	if string(data) != expected {
+		t.Fatalf("wrong schema:\n%v", string(data))
+	}
+}
+
+func TestFixedAndNullable(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package events
+
+type Token [8]byte
+
+type Block struct {
+	Hash   [16]byte
+	Parent [16]byte
+	Token  Token
+	Data   []byte
+	Count  **int
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "example.com/chain",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	r, err := avro.Generate(ps.Lookup("example.com/chain", "Block"), avro.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	expected := []string{
+		`{"type":"fixed","name":"Fixed16","namespace":"example.com.chain","size":16}`,
+		`"example.com.chain.Fixed16"`,
+		`{"type":"fixed","name":"Token","namespace":"example.com.chain","size":8}`,
+		`"bytes"`,
+		`["null","long"]`,
+	}
+	if len(r.Fields) != len(expected) {
+		t.Fatalf("wrong number of fields")
+	}
+	for idx, f := range r.Fields {
+		data, _ := json.Marshal(f.Type)
+		if string(data) != expected[idx] {
+			t.Fatalf("wrong type of field %v: %v", f.Name, string(data))
+		}
+	}
+
+	// The size of a fixed schema must be known
+	pf.Declarations[1].Type.(*parser.ParsedStruct).Fields[0].Type = &parser.ParsedArray{
+		Size:      "Size",
+		ValueType: &parser.ParsedNativeType{Name: "byte"},
+	}
+	_, err = avro.Generate(&pf.Declarations[1], avro.Options{})
+	if err == nil || err.Error() != "unable to convert field Hash of Block [err=array length Size must be a constant]" {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package forum
+
+type Page[T any] struct {
+	Items []T
+}
+
+type Comment struct {
+	Replies []Comment
+	Thread  *Page[Comment]
+}
+
+type Tree map[string]Tree
+
+type List []List
+
+type Forest struct {
+	Trees Tree
+}
+
+type Lists struct {
+	Items *List
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "example.com/forum",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	// Records, including instances of generic types, are referenced by name
+	r, err := avro.Generate(&pf.Declarations[1], avro.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+	data, _ := json.Marshal(r)
+	expected := `{"type":"record","name":"Comment","namespace":"example.com.forum","fields":[` +
+		`{"name":"Replies","type":{"type":"array","items":"example.com.forum.Comment"}},` +
+		`{"name":"Thread","type":["null",{"type":"record","name":"Page_Comment","namespace":"example.com.forum",` +
+		`"fields":[{"name":"Items","type":{"type":"array","items":"example.com.forum.Comment"}}]}],"default":null}]}`
+	if string(data) != expected {
+		t.Fatalf("wrong schema:\n%v", string(data))
+	}
+
+	// Other types cannot contain themselves
+	for _, tc := range []struct {
+		pd       *parser.ParsedDeclaration
+		expected string
+	}{
+		{&pf.Declarations[4], "unable to convert field Trees of Forest [err=invalid recursive type Tree]"},
+		{&pf.Declarations[5], "unable to convert field Items of Lists [err=invalid recursive type List]"},
+	} {
+		_, err = avro.Generate(tc.pd, avro.Options{})
+		if err == nil || err.Error() != tc.expected {
+			t.Fatalf("wrong error for %v: %v", tc.pd.Name, err)
+		}
+	}
+}
+
+func TestEnumSymbols(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package tasks
+
+type Priority int
+
+const (
+	High    Priority = 3
+	Low     Priority = 1
+	Medium  Priority = 2
+	Default          = Medium
+)
+
+type Color string
+
+const (
+	Red     Color = "red"
+	Crimson Color = "red"
+)
+
+type Shade string
+
+const (
+	LightBlue Shade = "light-blue"
+	DarkBlue  Shade = "dark_blue"
+)
+
+type Task struct {
+	Priority Priority
+	Color    Color
+	Shade    Shade
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "example.com/tasks",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	r, err := avro.Generate(ps.Lookup("example.com/tasks", "Task"), avro.Options{
+		Packages: ps,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	// Integer enums are sorted by value and skip repeated values, string values that collide or
+	// are not valid names fall back to the constant names
+	expected := [][]string{
+		{"Low", "Medium", "High"},
+		{"Red", "Crimson"},
+		{"LightBlue", "DarkBlue"},
+	}
+	for idx, f := range r.Fields {
+		symbols := f.Type.(*avro.Enum).Symbols
+		if strings.Join(symbols, ",") != strings.Join(expected[idx], ",") {
+			t.Fatalf("wrong symbols of %v: %v", f.Name, symbols)
+		}
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"UserName string `json:\"user_name\"`", "user_name"},
+		{"UserName string `json:\"user-name\" avro:\"user_name\"`", "user_name"},
+		{"UserName string `json:\"user-name\"`", "invalid Avro name user-name, set a valid one in the avro tag"},
+		{"UserName string `avro:\"1st\"`", "invalid Avro name 1st, set a valid one in the avro tag"},
+	} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content:  "package main\n\ntype User struct {\n\t" + tc.field + "\n}\n",
+			Filename: "test.go",
+			Module: parser.Module{
+				Name: "github.com/mxmauro/gofile-parser-test",
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+
+		r, err := avro.Generate(&pf.Declarations[0], avro.Options{})
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), "of User [err="+tc.expected+"]") {
+				t.Fatalf("wrong error for %v: %v", tc.field, err)
+			}
+		} else if r.Fields[0].Name != tc.expected {
+			t.Fatalf("wrong name for %v: %v", tc.field, r.Fields[0].Name)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"Name string `avro:\",default=guest\"`", `"guest"`},
+		{"Name *string `avro:\",default=null\"`", `null`},
+		{"Count int32 `avro:\",default=5\"`", `5`},
+		{"Ratio *float64 `avro:\",default=0.5\"`", `0.5`},
+		{"Active bool `avro:\",default=true\"`", `true`},
+		{"Name string `avro:\",default=123\"`", "default value 123 does not match the field type"},
+		{"Count int32 `avro:\",default=1.5\"`", "default value 1.5 does not match the field type"},
+		{"Count int32 `avro:\",default=none\"`", "invalid default value none"},
+		{"Active bool `avro:\",default=null\"`", "default value null does not match the field type"},
+		{"Tags []string `avro:\",default={}\"`", "default value {} does not match the field type"},
+	} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content:  "package main\n\ntype Settings struct {\n\t" + tc.field + "\n}\n",
+			Filename: "test.go",
+			Module: parser.Module{
+				Name: "github.com/mxmauro/gofile-parser-test",
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+
+		r, err := avro.Generate(&pf.Declarations[0], avro.Options{})
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), "[err="+tc.expected+"]") {
+				t.Fatalf("wrong error for %v: %v", tc.field, err)
+			}
+		} else if string(r.Fields[0].Default) != tc.expected {
+			t.Fatalf("wrong default value for %v: %v", tc.field, string(r.Fields[0].Default))
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package parser
+
+// -----------------------------------------------------------------------------
+
+type cloner struct {
+	decls     map[*ParsedDeclaration]*ParsedDeclaration
+	params    map[*ParsedField]*ParsedField
+	methods   map[*ParsedMethod]*ParsedMethod
+	refs      []*ParsedNonNativeType
+	paramRefs []*ParsedTypeParamRef
+
+	// Optional hooks to replace type parameters and generic indexes while copying. If a hook
+	// returns nil, the copied node is kept.
+	mapTypeParam func(ptpr *ParsedTypeParamRef) ParsedType
+	mapIndex     func(pi *ParsedIndex) ParsedType
+}
+
+// -----------------------------------------------------------------------------
+
+// Clone returns a deep copy of the parsed file. References to declarations of the same file are
+// remapped to the copied declarations, other references are kept.
+func Clone(pf *ParsedFile) *ParsedFile {
+	return CloneFiles([]*ParsedFile{pf})[0]
+}
+
+// CloneFiles returns a deep copy of a set of parsed files. References to declarations inside the
+// set are remapped to the copied declarations, so the copy can be modified without affecting the
+// original files.
+func CloneFiles(parsedFiles []*ParsedFile) []*ParsedFile {
+	c := newCloner()
+
+	clonedFiles := make([]*ParsedFile, 0, len(parsedFiles))
+	for _, pf := range parsedFiles {
+		clonedFiles = append(clonedFiles, c.copyFile(pf))
+	}
+	c.remap()
+
+	// Done
+	return clonedFiles
+}
+
+// CloneType returns a deep copy of the given type. References to declarations are kept.
+func CloneType(t ParsedType) ParsedType {
+	c := newCloner()
+	newT := c.copyType(t)
+	c.remap()
+	return newT
+}
+
+func newCloner() *cloner {
+	return &cloner{
+		decls:     make(map[*ParsedDeclaration]*ParsedDeclaration),
+		params:    make(map[*ParsedField]*ParsedField),
+		methods:   make(map[*ParsedMethod]*ParsedMethod),
+		refs:      make([]*ParsedNonNativeType, 0),
+		paramRefs: make([]*ParsedTypeParamRef, 0),
+	}
+}
+
+func (c *cloner) copyFile(pf *ParsedFile) *ParsedFile {
+	newPf := &ParsedFile{
+		Module:       pf.Module,
+		Filename:     pf.Filename,
+		Package:      pf.Package,
+		Declarations: make([]ParsedDeclaration, len(pf.Declarations)),
+		Constants:    make([]ParsedConstant, len(pf.Constants)),
+		Methods:      make([]ParsedMethod, len(pf.Methods)),
+		fileContent:  pf.fileContent,
+	}
+	if pf.Imports != nil {
+		newPf.Imports = append(make([]ParsedImport, 0, len(pf.Imports)), pf.Imports...)
+	}
+
+	for idx := range pf.Declarations {
+		pd := &pf.Declarations[idx]
+		newPd := &newPf.Declarations[idx]
+
+		newPd.Name = pd.Name
+		newPd.TypeParams = c.copyFields(pd.TypeParams)
+		newPd.Type = c.copyType(pd.Type)
+		newPd.Tags = copyTags(pd.Tags)
+		newPd.IsAlias = pd.IsAlias
+		newPd.Doc = pd.Doc
+		newPd.file = newPf
+
+		c.decls[pd] = newPd
+	}
+
+	for idx := range pf.Constants {
+		pc := &pf.Constants[idx]
+
+		newPf.Constants[idx] = ParsedConstant{
+			Name:        pc.Name,
+			Type:        c.copyType(pc.Type),
+			Value:       pc.Value,
+			Iota:        pc.Iota,
+			ParsedValue: pc.ParsedValue, // Constant values are immutable
+			valueExpr:   pc.valueExpr,
+		}
+	}
+
+	for idx := range pf.Methods {
+		pm := &pf.Methods[idx]
+
+		newPf.Methods[idx] = ParsedMethod{
+			Name:               pm.Name,
+			Receiver:           pm.Receiver,
+			PointerReceiver:    pm.PointerReceiver,
+			ReceiverTypeParams: c.copyFields(pm.ReceiverTypeParams),
+		}
+		if pm.Type != nil {
+			newPf.Methods[idx].Type = c.copyType(pm.Type).(*ParsedFunction)
+		}
+		c.methods[pm] = &newPf.Methods[idx]
+	}
+
+	// Done
+	return newPf
+}
+
+func (c *cloner) copyType(t ParsedType) ParsedType {
+	switch tType := t.(type) {
+	case *ParsedNativeType:
+		return &ParsedNativeType{
+			Name: tType.Name,
+		}
+
+	case *ParsedNonNativeType:
+		pnnt := &ParsedNonNativeType{
+			Name:       tType.Name,
+			ImportPath: tType.ImportPath,
+			Ref:        tType.Ref,
+		}
+		c.refs = append(c.refs, pnnt)
+		return pnnt
+
+	case *ParsedTypeParamRef:
+		if c.mapTypeParam != nil {
+			if newT := c.mapTypeParam(tType); newT != nil {
+				return newT
+			}
+		}
+		ptpr := &ParsedTypeParamRef{
+			Name:  tType.Name,
+			Param: tType.Param,
+		}
+		c.paramRefs = append(c.paramRefs, ptpr)
+		return ptpr
+
+	case *ParsedStruct:
+		return &ParsedStruct{
+			Fields: c.copyFields(tType.Fields),
+		}
+
+	case *ParsedInterface:
+		return &ParsedInterface{
+			Methods:    c.copyFields(tType.Methods),
+			Incomplete: tType.Incomplete,
+		}
+
+	case *ParsedMap:
+		return &ParsedMap{
+			KeyType:   c.copyType(tType.KeyType),
+			ValueType: c.copyType(tType.ValueType),
+		}
+
+	case *ParsedArray:
+		pa := &ParsedArray{
+			Size:      tType.Size,
+			SizeError: tType.SizeError,
+			ValueType: c.copyType(tType.ValueType),
+			sizeExpr:  tType.sizeExpr,
+		}
+		if tType.ParsedInt != nil {
+			parsedInt := *tType.ParsedInt
+			pa.ParsedInt = &parsedInt
+		}
+		return pa
+
+	case *ParsedPointer:
+		return &ParsedPointer{
+			ToType: c.copyType(tType.ToType),
+		}
+
+	case *ParsedChannel:
+		return &ParsedChannel{
+			Dir:  tType.Dir,
+			Type: c.copyType(tType.Type),
+		}
+
+	case *ParsedFunction:
+		return &ParsedFunction{
+			TypeParams: c.copyFields(tType.TypeParams),
+			Params:     c.copyFields(tType.Params),
+			Results:    c.copyFields(tType.Results),
+			Variadic:   tType.Variadic,
+		}
+
+	case *ParsedIndex:
+		pi := &ParsedIndex{
+			Type: c.copyType(tType.Type),
+		}
+		if tType.Indexes != nil {
+			pi.Indexes = make([]ParsedType, 0, len(tType.Indexes))
+			for _, idx := range tType.Indexes {
+				pi.Indexes = append(pi.Indexes, c.copyType(idx))
+			}
+		}
+		if c.mapIndex != nil {
+			if newT := c.mapIndex(pi); newT != nil {
+				return newT
+			}
+		}
+		return pi
+
+	case *ParsedUnion:
+		pu := &ParsedUnion{}
+		if tType.Terms != nil {
+			pu.Terms = make([]ParsedUnionTerm, 0, len(tType.Terms))
+			for _, term := range tType.Terms {
+				pu.Terms = append(pu.Terms, ParsedUnionTerm{
+					Tilde: term.Tilde,
+					Type:  c.copyType(term.Type),
+				})
+			}
+		}
+		return pu
+	}
+	return nil
+}
+
+func (c *cloner) copyFields(fields []ParsedField) []ParsedField {
+	if fields == nil {
+		return nil
+	}
+
+	newFields := make([]ParsedField, len(fields))
+	for idx := range fields {
+		field := &fields[idx]
+
+		newFields[idx] = ParsedField{
+			Names:        append(make([]string, 0, len(field.Names)), field.Names...),
+			ImplicitName: field.ImplicitName,
+			Type:         c.copyType(field.Type),
+			Tags:         copyTags(field.Tags),
+			Doc:          field.Doc,
+			rawTag:       field.rawTag,
+		}
+		c.params[field] = &newFields[idx]
+	}
+	return newFields
+}
+
+// remap updates the copied references so they point to the copied declarations, type parameters
+// and methods
+func (c *cloner) remap() {
+	for pd, newPd := range c.decls {
+		if pd.Methods != nil {
+			newPd.Methods = make([]*ParsedMethod, 0, len(pd.Methods))
+			for _, pm := range pd.Methods {
+				if newPm, ok := c.methods[pm]; ok {
+					pm = newPm
+				}
+				newPd.Methods = append(newPd.Methods, pm)
+			}
+		}
+	}
+	for _, pnnt := range c.refs {
+		if newPd, ok := c.decls[pnnt.Ref]; ok {
+			pnnt.Ref = newPd
+		}
+	}
+	for _, ptpr := range c.paramRefs {
+		if newParam, ok := c.params[ptpr.Param]; ok {
+			ptpr.Param = newParam
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func copyTags(tags ParsedTags) ParsedTags {
+	if tags == nil {
+		return nil
+	}
+	newTags := make(ParsedTags, len(tags))
+	for k, v := range tags {
+		newTags[k] = v
+	}
+	return newTags
+}
@@ -0,0 +1,318 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"math"
+)
+
+// -----------------------------------------------------------------------------
+
+func (rr *refResolver) evalArraySize(pa *ParsedArray) {
+	v, err := rr.evalConstExpr(rr.currentFile, pa.sizeExpr, -1)
+	if err == nil {
+		v = constant.ToInt(v)
+		if v.Kind() != constant.Int {
+			err = fmt.Errorf("array length %s must be an integer", pa.Size)
+		}
+	}
+	if err == nil {
+		parsedInt, exact := constant.Int64Val(v)
+		if (!exact) || parsedInt < 0 {
+			err = fmt.Errorf("invalid array length %s", pa.Size)
+		} else {
+			pa.ParsedInt = &parsedInt
+		}
+	}
+	pa.SizeError = err
+}
+
+func (rr *refResolver) evalConstant(pf *ParsedFile, pc *ParsedConstant) (constant.Value, error) {
+	if pc.ParsedValue != nil {
+		return pc.ParsedValue, nil // Already evaluated
+	}
+	if pc.valueExpr == nil {
+		return nil, fmt.Errorf("constant %s has no value", pc.Name)
+	}
+
+	if _, ok := rr.evaluatingConstants[pc]; ok {
+		return nil, fmt.Errorf("constant %s has a circular definition", pc.Name)
+	}
+	rr.evaluatingConstants[pc] = struct{}{}
+	defer delete(rr.evaluatingConstants, pc)
+
+	v, err := rr.evalConstExpr(pf, pc.valueExpr, pc.Iota)
+	if err != nil {
+		return nil, err
+	}
+	if pnt, ok := pc.Type.(*ParsedNativeType); ok {
+		v, err = convertConstant(v, pnt.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pc.ParsedValue = v
+
+	// Done
+	return v, nil
+}
+
+func (rr *refResolver) evalConstExpr(pf *ParsedFile, expr ast.Expr, iota int) (v constant.Value, err error) {
+	// The go/constant package panics on operations with mismatched operands
+	defer func() {
+		if r := recover(); r != nil {
+			v = nil
+			err = fmt.Errorf("invalid constant expression %s", pf.sourceText(expr))
+		}
+	}()
+
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return rr.evalConstExpr(pf, e.X, iota)
+
+	case *ast.BasicLit:
+		v = constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		if v.Kind() == constant.Unknown {
+			return nil, fmt.Errorf("invalid literal %s", e.Value)
+		}
+		return v, nil
+
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			if iota < 0 {
+				return nil, errors.New("iota used outside constant declaration")
+			}
+			return constant.MakeInt64(int64(iota)), nil
+		case "true":
+			return constant.MakeBool(true), nil
+		case "false":
+			return constant.MakeBool(false), nil
+		}
+		return rr.evalNamedConstant(pf, "", e.Name)
+
+	case *ast.SelectorExpr:
+		if xIdent, ok := e.X.(*ast.Ident); ok {
+			return rr.evalNamedConstant(pf, xIdent.Name, e.Sel.Name)
+		}
+
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case token.ADD, token.SUB, token.XOR, token.NOT:
+			v, err = rr.evalConstExpr(pf, e.X, iota)
+			if err != nil {
+				return nil, err
+			}
+			return constant.UnaryOp(e.Op, v, 0), nil
+		}
+
+	case *ast.BinaryExpr:
+		var x, y constant.Value
+
+		x, err = rr.evalConstExpr(pf, e.X, iota)
+		if err != nil {
+			return nil, err
+		}
+		y, err = rr.evalConstExpr(pf, e.Y, iota)
+		if err != nil {
+			return nil, err
+		}
+
+		switch e.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(constant.ToInt(y))
+			if !ok {
+				return nil, fmt.Errorf("invalid shift count %s", pf.sourceText(e.Y))
+			}
+			return constant.Shift(x, e.Op, uint(s)), nil
+
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return constant.MakeBool(constant.Compare(x, e.Op, y)), nil
+
+		case token.QUO, token.REM:
+			if constant.Sign(y) == 0 {
+				return nil, errors.New("division by zero")
+			}
+			if e.Op == token.QUO && x.Kind() == constant.Int && y.Kind() == constant.Int {
+				return constant.BinaryOp(x, token.QUO_ASSIGN, y), nil // Force integer division
+			}
+		}
+		return constant.BinaryOp(x, e.Op, y), nil
+
+	case *ast.CallExpr:
+		return rr.evalConstCall(pf, e, iota)
+	}
+
+	// Not supported
+	return nil, fmt.Errorf("unsupported constant expression %s", pf.sourceText(expr))
+}
+
+func (rr *refResolver) evalConstCall(pf *ParsedFile, call *ast.CallExpr, iota int) (constant.Value, error) {
+	if len(call.Args) != 1 || call.Ellipsis.IsValid() {
+		return nil, fmt.Errorf("unsupported constant expression %s", pf.sourceText(call))
+	}
+
+	fun := call.Fun
+	for {
+		parenExpr, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = parenExpr.X
+	}
+
+	funcName := ""
+	switch f := fun.(type) {
+	case *ast.Ident:
+		funcName = f.Name
+	case *ast.SelectorExpr:
+		if xIdent, ok := f.X.(*ast.Ident); ok {
+			funcName = xIdent.Name + "." + f.Sel.Name
+		}
+	}
+
+	v, err := rr.evalConstExpr(pf, call.Args[0], iota)
+	if err != nil {
+		return nil, err
+	}
+
+	// Builtin len applied to a constant string
+	if funcName == "len" {
+		if v.Kind() != constant.String {
+			return nil, fmt.Errorf("unsupported constant expression %s", pf.sourceText(call))
+		}
+		return constant.MakeInt64(int64(len(constant.StringVal(v)))), nil
+	}
+
+	// Conversion to a native type
+	if IsNativeType(funcName) {
+		return convertConstant(v, funcName)
+	}
+
+	// Conversion to a declared type
+	if len(funcName) > 0 && rr.findDeclaration(pf, funcName) != nil {
+		return v, nil
+	}
+
+	// Not supported
+	return nil, fmt.Errorf("unsupported constant expression %s", pf.sourceText(call))
+}
+
+func (rr *refResolver) evalNamedConstant(pf *ParsedFile, packageName string, name string) (constant.Value, error) {
+	moduleName, ok := rr.resolvePackage(pf, packageName)
+	if ok {
+		if ic, found := rr.packages.constants[moduleName][name]; found {
+			return rr.evalConstant(ic.pf, ic.pc)
+		}
+	}
+
+	if len(packageName) > 0 {
+		name = packageName + "." + name
+	}
+	return nil, fmt.Errorf("unable to resolve constant %s", name)
+}
+
+// -----------------------------------------------------------------------------
+
+type integerSize struct {
+	bits   uint
+	signed bool
+}
+
+// -----------------------------------------------------------------------------
+
+var integerSizes = map[string]integerSize{
+	"int8":    {8, true},
+	"int16":   {16, true},
+	"int32":   {32, true},
+	"rune":    {32, true},
+	"int64":   {64, true},
+	"int":     {64, true},
+	"uint8":   {8, false},
+	"byte":    {8, false},
+	"uint16":  {16, false},
+	"uint32":  {32, false},
+	"uint64":  {64, false},
+	"uint":    {64, false},
+	"uintptr": {64, false},
+}
+
+// -----------------------------------------------------------------------------
+
+func convertConstant(v constant.Value, nativeType string) (constant.Value, error) {
+	var converted constant.Value
+
+	switch nativeType {
+	case "bool":
+		if v.Kind() == constant.Bool {
+			converted = v
+		}
+
+	case "string":
+		switch v.Kind() {
+		case constant.String:
+			converted = v
+		case constant.Int:
+			if code, ok := constant.Int64Val(v); ok {
+				converted = constant.MakeString(string(rune(code)))
+			}
+		}
+
+	case "float", "float32", "float64":
+		converted = constant.ToFloat(v)
+
+	case "complex64", "complex128":
+		converted = constant.ToComplex(v)
+
+	default:
+		converted = constant.ToInt(v)
+	}
+
+	if converted == nil || converted.Kind() == constant.Unknown {
+		return nil, fmt.Errorf("cannot convert %s to %s", v.ExactString(), nativeType)
+	}
+	if !representable(converted, nativeType) {
+		return nil, fmt.Errorf("constant %s overflows %s", v.ExactString(), nativeType)
+	}
+
+	// Done
+	return converted, nil
+}
+
+// representable checks if the value fits in the native type like the type checker does. The int,
+// uint and uintptr types are assumed to be 64-bit wide.
+func representable(v constant.Value, nativeType string) bool {
+	switch nativeType {
+	case "float32":
+		f, _ := constant.Float32Val(v)
+		return !math.IsInf(float64(f), 0)
+
+	case "float", "float64":
+		f, _ := constant.Float64Val(v)
+		return !math.IsInf(f, 0)
+
+	case "complex64":
+		return representable(constant.Real(v), "float32") && representable(constant.Imag(v), "float32")
+
+	case "complex128":
+		return representable(constant.Real(v), "float64") && representable(constant.Imag(v), "float64")
+	}
+
+	size, ok := integerSizes[nativeType]
+	if !ok {
+		return true
+	}
+	var min, max constant.Value
+	if size.signed {
+		min = constant.Shift(constant.MakeInt64(-1), token.SHL, size.bits-1)
+		max = constant.Shift(constant.MakeInt64(1), token.SHL, size.bits-1)
+	} else {
+		min = constant.MakeInt64(0)
+		max = constant.Shift(constant.MakeInt64(1), token.SHL, size.bits)
+	}
+	return constant.Compare(v, token.GEQ, min) && constant.Compare(v, token.LSS, max)
+}
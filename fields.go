@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// FlattenedField is a field of a struct, either declared directly or promoted from an embedded
+// field.
+type FlattenedField struct {
+	Name       string
+	Field      *ParsedField // The field definition, it can contain other names too
+	Path       []string     // Selector path to access the field, e.g. ["Base", "ID"]
+	Depth      int          // Zero for fields declared in the struct itself
+	ViaPointer bool         // True if the field is promoted through an embedded pointer
+	Embedded   bool         // True if the field itself is an embedded field
+}
+
+// JSONField is a field of a struct as encoded by the encoding/json package.
+type JSONField struct {
+	Name      string       // The name of the JSON property
+	Field     *ParsedField // The field definition
+	Depth     int          // Zero for fields declared in the struct itself
+	Tagged    bool         // True if the name is given by the json tag
+	OmitEmpty bool         // True if the omitempty option is set
+	AsString  bool         // True if the string option is set
+}
+
+type embeddedStruct struct {
+	ps         *ParsedStruct
+	key        string
+	path       []string
+	viaPointer bool
+}
+
+// -----------------------------------------------------------------------------
+
+// FlattenedFields returns the effective field set of the struct following Go's selector rules.
+// Fields of embedded structs are promoted unless a field with the same name exists at a lower
+// depth. If more than one field with the same name exists at the same depth, the selector is
+// ambiguous and none of them is returned. Embedded pointers and embedded generic types are
+// followed through resolved references.
+func (ps *ParsedStruct) FlattenedFields() []FlattenedField {
+	fields := make([]FlattenedField, 0)
+	blocked := make(map[string]struct{})
+	seen := make(map[string]struct{})
+
+	current := []embeddedStruct{
+		{
+			ps:   ps,
+			path: make([]string, 0),
+		},
+	}
+	for depth := 0; len(current) > 0; depth++ {
+		candidates := make(map[string][]FlattenedField)
+		order := make([]string, 0)
+		next := make([]embeddedStruct, 0)
+
+		for _, es := range current {
+			for fieldIdx := range es.ps.Fields {
+				field := &es.ps.Fields[fieldIdx]
+
+				for _, name := range field.Identifiers() {
+					if len(name) == 0 {
+						continue
+					}
+
+					if _, ok := candidates[name]; !ok {
+						order = append(order, name)
+					}
+					candidates[name] = append(candidates[name], FlattenedField{
+						Name:       name,
+						Field:      field,
+						Path:       append(append(make([]string, 0, len(es.path)+1), es.path...), name),
+						Depth:      depth,
+						ViaPointer: es.viaPointer,
+						Embedded:   len(field.Names) == 0,
+					})
+				}
+
+				// Queue embedded structs for the next depth
+				if len(field.Names) == 0 && len(field.ImplicitName) > 0 {
+					embedded, key, isPointer := resolveEmbeddedStruct(field.Type)
+					if embedded == nil {
+						continue
+					}
+					if len(key) > 0 {
+						if _, ok := seen[key]; ok {
+							continue // Already processed at a lower depth
+						}
+					}
+					next = append(next, embeddedStruct{
+						ps:         embedded,
+						key:        key,
+						path:       append(append(make([]string, 0, len(es.path)+1), es.path...), field.ImplicitName),
+						viaPointer: es.viaPointer || isPointer,
+					})
+				}
+			}
+		}
+
+		for _, name := range order {
+			if _, ok := blocked[name]; ok {
+				continue // Shadowed by a field at a lower depth or ambiguous
+			}
+			blocked[name] = struct{}{}
+			if len(candidates[name]) == 1 {
+				fields = append(fields, candidates[name][0])
+			}
+		}
+
+		// Named types queued for the next depth must not be processed again deeper
+		for _, es := range next {
+			if len(es.key) > 0 {
+				seen[es.key] = struct{}{}
+			}
+		}
+
+		current = next
+	}
+
+	// Done
+	return fields
+}
+
+// JSONFields returns the fields of the struct following the encoding/json rules. Fields of
+// embedded structs without a json name are inlined. If more than one field has the same name,
+// the one with the lowest depth wins; if several exist at the same depth, the tagged one wins,
+// else all of them are discarded.
+func (ps *ParsedStruct) JSONFields() []JSONField {
+	fields := collectJSONFields(ps, 0, make(map[*ParsedStruct]struct{}))
+
+	byName := make(map[string][]JSONField)
+	order := make([]string, 0)
+	for _, jf := range fields {
+		if _, ok := byName[jf.Name]; !ok {
+			order = append(order, jf.Name)
+		}
+		byName[jf.Name] = append(byName[jf.Name], jf)
+	}
+
+	result := make([]JSONField, 0, len(order))
+	for _, name := range order {
+		candidates := byName[name]
+		if len(candidates) == 1 {
+			result = append(result, candidates[0])
+			continue
+		}
+
+		minDepth := candidates[0].Depth
+		for _, jf := range candidates[1:] {
+			if jf.Depth < minDepth {
+				minDepth = jf.Depth
+			}
+		}
+		var dominant *JSONField
+		count := 0
+		taggedCount := 0
+		for idx := range candidates {
+			if candidates[idx].Depth != minDepth {
+				continue
+			}
+			count += 1
+			if candidates[idx].Tagged {
+				taggedCount += 1
+				dominant = &candidates[idx]
+			} else if taggedCount == 0 {
+				dominant = &candidates[idx]
+			}
+		}
+		if count == 1 || taggedCount == 1 {
+			result = append(result, *dominant)
+		}
+	}
+
+	// Done
+	return result
+}
+
+// -----------------------------------------------------------------------------
+
+// resolveEmbeddedStruct returns the struct definition of an embedded field along with a key that
+// identifies the named type, and if the field is a pointer.
+func resolveEmbeddedStruct(t ParsedType) (*ParsedStruct, string, bool) {
+	isPointer := false
+	if pp, ok := t.(*ParsedPointer); ok {
+		isPointer = true
+		t = pp.ToType
+	}
+
+	key := ""
+	switch tType := t.(type) {
+	case *ParsedNonNativeType:
+		if tType.Ref == nil {
+			return nil, "", isPointer
+		}
+		key = fmt.Sprintf("%p", tType.Ref)
+
+	case *ParsedIndex:
+		if pnnt, ok := tType.Type.(*ParsedNonNativeType); ok && pnnt.Ref != nil {
+			key = fmt.Sprintf("%p", pnnt.Ref) + Render(tType, nil)
+		}
+
+	default:
+		return nil, "", isPointer
+	}
+
+	ps, _ := Underlying(t).(*ParsedStruct)
+	return ps, key, isPointer
+}
+
+// collectJSONFields returns the candidate JSON fields of a struct, including the ones of embedded
+// structs without a json name.
+func collectJSONFields(ps *ParsedStruct, depth int, visited map[*ParsedStruct]struct{}) []JSONField {
+	if _, ok := visited[ps]; ok {
+		return nil
+	}
+	visited[ps] = struct{}{}
+	defer delete(visited, ps)
+
+	fields := make([]JSONField, 0)
+	for fieldIdx := range ps.Fields {
+		field := &ps.Fields[fieldIdx]
+
+		tag, _ := field.Tags.GetTag("json")
+		if tag == "-" {
+			continue
+		}
+		options := strings.Split(string(tag), ",")
+		tagName := options[0]
+		tagged := len(tagName) > 0
+
+		if len(field.Names) == 0 {
+			if !tagged {
+				t := Unalias(field.Type)
+				if pp, ok := t.(*ParsedPointer); ok {
+					t = pp.ToType
+				}
+				if embedded, ok := Underlying(t).(*ParsedStruct); ok {
+					fields = append(fields, collectJSONFields(embedded, depth+1, visited)...)
+					continue
+				}
+			}
+			if !IsPublic(field.ImplicitName) {
+				continue
+			}
+		}
+
+		for _, name := range field.Identifiers() {
+			if len(field.Names) > 0 && !IsPublic(name) {
+				continue
+			}
+			jf := JSONField{
+				Name:      name,
+				Field:     field,
+				Depth:     depth,
+				Tagged:    tagged,
+				OmitEmpty: hasTagOption(options[1:], "omitempty"),
+				AsString:  hasTagOption(options[1:], "string"),
+			}
+			if tagged {
+				jf.Name = tagName
+			}
+			fields = append(fields, jf)
+		}
+	}
+
+	// Done
+	return fields
+}
+
+func hasTagOption(options []string, option string) bool {
+	for _, opt := range options {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// -----------------------------------------------------------------------------
+
+const maxInstantiationDepth = 32
+
+type typeParamKey struct {
+	param *ParsedField
+	name  string
+}
+
+type instantiator struct {
+	instances map[string]*ParsedDeclaration
+	depth     int
+}
+
+// -----------------------------------------------------------------------------
+
+// Instantiate returns a new declaration built from the generic declaration referenced by the
+// index expression, where every type parameter is replaced by the matching type argument.
+// Generic types used inside the declaration are also instantiated and referenced through
+// ParsedNonNativeType nodes. References must be resolved before calling this function.
+func Instantiate(pi *ParsedIndex) (*ParsedDeclaration, error) {
+	inst := instantiator{
+		instances: make(map[string]*ParsedDeclaration),
+	}
+	return inst.instantiate(pi)
+}
+
+func (inst *instantiator) instantiate(pi *ParsedIndex) (*ParsedDeclaration, error) {
+	pnnt, ok := pi.Type.(*ParsedNonNativeType)
+	if !ok {
+		return nil, errors.New("index expression does not reference a declaration")
+	}
+	generic := pnnt.Ref
+	if generic == nil {
+		return nil, fmt.Errorf("unresolved reference to %s", pnnt.Name)
+	}
+
+	// Map type parameters to their arguments
+	args := make(map[typeParamKey]ParsedType)
+	for tpIdx := range generic.TypeParams {
+		tp := &generic.TypeParams[tpIdx]
+		for _, name := range tp.Names {
+			if len(args) >= len(pi.Indexes) {
+				return nil, fmt.Errorf("not enough type arguments for %s", generic.Name)
+			}
+			args[typeParamKey{param: tp, name: name}] = pi.Indexes[len(args)]
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s is not a generic type", generic.Name)
+	}
+	if len(args) != len(pi.Indexes) {
+		return nil, fmt.Errorf("too many type arguments for %s", generic.Name)
+	}
+
+	// Reuse the instance if it was already created, this handles recursive generic types
+	name := Render(pi, nil)
+	key := fmt.Sprintf("%p:%s", generic, name)
+	if pd, ok := inst.instances[key]; ok {
+		return pd, nil
+	}
+
+	if inst.depth >= maxInstantiationDepth {
+		return nil, fmt.Errorf("instantiation of %s is too deep", name)
+	}
+	inst.depth += 1
+	defer func() {
+		inst.depth -= 1
+	}()
+
+	pd := &ParsedDeclaration{
+		Name:       name,
+		Tags:       copyTags(generic.Tags),
+		IsAlias:    generic.IsAlias,
+		file:       generic.file,
+		instanceOf: pi,
+	}
+	inst.instances[key] = pd
+
+	t, err := inst.substitute(generic.Type, args)
+	if err != nil {
+		delete(inst.instances, key)
+		return nil, fmt.Errorf("unable to instantiate %s [err=%v]", name, err)
+	}
+	pd.Type = t
+
+	// Done
+	return pd, nil
+}
+
+// substitute returns a copy of the type where type parameters are replaced by their arguments.
+// It fails if a nested generic type cannot be instantiated.
+func (inst *instantiator) substitute(t ParsedType, args map[typeParamKey]ParsedType) (ParsedType, error) {
+	var err error
+
+	c := newCloner()
+	c.mapTypeParam = func(ptpr *ParsedTypeParamRef) ParsedType {
+		if arg, ok := args[typeParamKey{param: ptpr.Param, name: ptpr.Name}]; ok {
+			newArg, argErr := inst.substitute(arg, nil)
+			if argErr != nil && err == nil {
+				err = argErr
+			}
+			return newArg
+		}
+		return nil
+	}
+	c.mapIndex = func(pi *ParsedIndex) ParsedType {
+		// Instantiate nested generic types
+		if err != nil {
+			return nil
+		}
+		pd, instErr := inst.instantiate(pi)
+		if instErr != nil {
+			err = instErr
+			return nil
+		}
+		return &ParsedNonNativeType{
+			Name: pd.Name,
+			Ref:  pd,
+		}
+	}
+	newT := c.copyType(t)
+	c.remap()
+	if err != nil {
+		return nil, err
+	}
+
+	// Done
+	return newT, nil
+}
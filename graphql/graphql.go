@@ -0,0 +1,637 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+// DefaultTagKey is the struct tag key used to customize a field, for example,
+// `graphql:"name,nullable"`. The `nonnull` option makes a pointer field non-null, the `type`
+// property overrides the type name, for example, `graphql:",type=ID"`, and `-` skips the field.
+const DefaultTagKey = "graphql"
+
+const (
+	KindObject TypeKind = iota
+	KindInterface
+	KindEnum
+	KindScalar
+)
+
+type Options struct {
+	// Packages, if set, is used to find the constants declared with a named type, which are
+	// emitted as enums, and the structs that implement the converted interfaces.
+	Packages *parser.PackageSet
+
+	// TagKey is the struct tag key that contains the field overrides, defaults to DefaultTagKey
+	TagKey string
+}
+
+// TypeKind is the kind of GraphQL type definition
+type TypeKind int
+
+// Schema is a set of GraphQL type definitions
+type Schema struct {
+	Types []*Type
+
+	// Warnings contains the fields that were skipped because their types, like maps, cannot be
+	// represented in GraphQL
+	Warnings []string
+}
+
+// Type is an object, interface, enum or scalar type definition
+type Type struct {
+	Kind        TypeKind
+	Name        string
+	Description string
+	Interfaces  []string // Interfaces implemented by an object type
+	Fields      []*Field
+	Values      []string // Values of an enum type
+}
+
+// Field is a field of an object or interface type
+type Field struct {
+	Name        string
+	Type        string // The type reference, like [String!]!
+	Description string
+}
+
+type generator struct {
+	opts     Options
+	schema   *Schema
+	types    map[string]*Type
+	names    map[string]string // GraphQL type names by declaration key
+	visiting map[string]struct{}
+	queue    []queuedType
+	ifaces   []queuedType
+	objects  []queuedType
+}
+
+type queuedType struct {
+	t   *Type
+	pd  *parser.ParsedDeclaration
+	ref parser.ParsedType
+}
+
+type unsupportedError struct {
+	reason string
+}
+
+// -----------------------------------------------------------------------------
+
+var wellKnownTypes = map[string]string{
+	"time.Time":                   "Time",
+	"time.Duration":               "Int",
+	"net/url.URL":                 "String",
+	"github.com/google/uuid.UUID": "ID",
+}
+
+// Generate converts the given declarations, and the ones they reference, to GraphQL type
+// definitions: structs become object types, interfaces become interface types and named types
+// with constants become enums. References must be resolved before calling this function.
+func Generate(decls []*parser.ParsedDeclaration, opts Options) (*Schema, error) {
+	if len(opts.TagKey) == 0 {
+		opts.TagKey = DefaultTagKey
+	}
+
+	g := generator{
+		opts: opts,
+		schema: &Schema{
+			Types:    make([]*Type, 0),
+			Warnings: make([]string, 0),
+		},
+		types:    make(map[string]*Type),
+		names:    make(map[string]string),
+		visiting: make(map[string]struct{}),
+		queue:    make([]queuedType, 0),
+		ifaces:   make([]queuedType, 0),
+		objects:  make([]queuedType, 0),
+	}
+
+	for _, pd := range decls {
+		if len(pd.TypeParams) > 0 {
+			return nil, fmt.Errorf("generic declaration %s must be instantiated", pd.Name)
+		}
+		_, err := g.namedType(pd, pd.Name, &parser.ParsedNonNativeType{
+			Name: pd.Name,
+			Ref:  pd,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for len(g.queue) > 0 {
+		qt := g.queue[0]
+		g.queue = g.queue[1:]
+
+		err := g.fill(qt)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert %s [err=%v]", qt.pd.Name, err)
+		}
+	}
+
+	g.linkInterfaces()
+
+	// Done
+	return g.schema, nil
+}
+
+// String returns the schema in the GraphQL schema definition language
+func (s *Schema) String() string {
+	sb := strings.Builder{}
+
+	for idx, t := range s.Types {
+		if idx > 0 {
+			sb.WriteString("\n")
+		}
+		writeDescription(&sb, t.Description, "")
+
+		switch t.Kind {
+		case KindScalar:
+			sb.WriteString("scalar " + t.Name + "\n")
+			continue
+
+		case KindEnum:
+			sb.WriteString("enum " + t.Name + " {\n")
+			for _, value := range t.Values {
+				sb.WriteString("  " + value + "\n")
+			}
+			sb.WriteString("}\n")
+			continue
+
+		case KindInterface:
+			sb.WriteString("interface " + t.Name)
+
+		default:
+			sb.WriteString("type " + t.Name)
+			if len(t.Interfaces) > 0 {
+				sb.WriteString(" implements " + strings.Join(t.Interfaces, " & "))
+			}
+		}
+
+		sb.WriteString(" {\n")
+		for _, f := range t.Fields {
+			writeDescription(&sb, f.Description, "  ")
+			sb.WriteString("  " + f.Name + ": " + f.Type + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+
+	// Done
+	return sb.String()
+}
+
+// -----------------------------------------------------------------------------
+
+// namedType returns the name of the GraphQL type of a declaration, queueing its definition if
+// needed. An empty name is returned if the declaration is represented by a built-in scalar.
+func (g *generator) namedType(pd *parser.ParsedDeclaration, name string, ref parser.ParsedType) (string, error) {
+	key := codegen.DeclarationKey(pd)
+	if typeName, ok := g.names[key]; ok {
+		return typeName, nil
+	}
+	name = g.uniqueName(name)
+
+	t := &Type{
+		Name:        name,
+		Description: pd.Doc,
+	}
+
+	// Types with custom marshallers are custom scalars
+	if len(codegen.Marshaler(ref)) > 0 {
+		t.Kind = KindScalar
+		g.addNamedType(key, t)
+		return name, nil
+	}
+
+	if g.opts.Packages != nil && len(pd.TypeParams) == 0 {
+		if values := enumValues(g.opts.Packages.EnumValues(pd)); len(values) > 0 {
+			t.Kind = KindEnum
+			t.Values = values
+			g.addNamedType(key, t)
+			return name, nil
+		}
+	}
+
+	switch underlying := parser.Underlying(ref).(type) {
+	case *parser.ParsedStruct:
+		t.Kind = KindObject
+	case *parser.ParsedInterface:
+		if len(underlying.Methods) == 0 {
+			return "", &unsupportedError{
+				reason: "empty interfaces cannot be represented in GraphQL",
+			}
+		}
+		t.Kind = KindInterface
+	default:
+		return "", nil // Represented by the scalar of the underlying type
+	}
+
+	g.addNamedType(key, t)
+	g.queue = append(g.queue, queuedType{
+		t:   t,
+		pd:  pd,
+		ref: ref,
+	})
+	return name, nil
+}
+
+func (g *generator) addType(t *Type) {
+	g.types[t.Name] = t
+	g.schema.Types = append(g.schema.Types, t)
+}
+
+// addNamedType adds the type definition of a declaration
+func (g *generator) addNamedType(key string, t *Type) {
+	g.names[key] = t.Name
+	g.addType(t)
+}
+
+// uniqueName appends a number to a type name already used by a declaration of another package,
+// like User2
+func (g *generator) uniqueName(name string) string {
+	if _, used := g.types[name]; !used {
+		return name
+	}
+	for idx := 2; ; idx++ {
+		candidate := fmt.Sprintf("%s%d", name, idx)
+		if _, used := g.types[candidate]; !used {
+			return candidate
+		}
+	}
+}
+
+func (g *generator) scalar(name string) string {
+	switch name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return name
+	}
+	if _, ok := g.types[name]; !ok {
+		g.addType(&Type{
+			Kind: KindScalar,
+			Name: name,
+		})
+	}
+	return name
+}
+
+// fill adds the fields of a queued object or interface type
+func (g *generator) fill(qt queuedType) error {
+	qt.t.Fields = make([]*Field, 0)
+
+	if qt.t.Kind == KindInterface {
+		g.ifaces = append(g.ifaces, qt)
+		g.addImplementations(qt)
+		return g.interfaceFields(qt)
+	}
+
+	g.objects = append(g.objects, qt)
+	ps := parser.Underlying(qt.ref).(*parser.ParsedStruct)
+	for _, jf := range ps.JSONFields() {
+		name := jf.Name
+		if !jf.Tagged {
+			name = lowerCamelCase(name)
+		}
+
+		nullable := false
+		nonNull := false
+		override := ""
+		if tag, ok := jf.Field.Tags.GetTag(g.opts.TagKey); ok {
+			if tag == "-" {
+				continue
+			}
+			tagName, props := codegen.SplitTag(tag)
+			if len(tagName) > 0 {
+				name = tagName
+			}
+			nullable = props.HasProperty("nullable")
+			nonNull = props.HasProperty("nonnull")
+			override, _ = props.GetProperty("type")
+		}
+
+		ft, err := g.typeRef(jf.Field.Type)
+		if err != nil {
+			if unsupported, ok := err.(*unsupportedError); ok {
+				g.warn(qt.t.Name, jf.Name, unsupported.reason)
+				continue
+			}
+			return fmt.Errorf("unable to convert field %s [err=%v]", jf.Name, err)
+		}
+		if len(override) > 0 {
+			ft = replaceNamedType(ft, override)
+		}
+		if nullable {
+			ft = strings.TrimSuffix(ft, "!")
+		} else if nonNull && !strings.HasSuffix(ft, "!") {
+			ft += "!"
+		}
+
+		qt.t.Fields = append(qt.t.Fields, &Field{
+			Name:        name,
+			Type:        ft,
+			Description: jf.Field.Doc,
+		})
+	}
+
+	// Done
+	return nil
+}
+
+// interfaceFields converts the getters of an interface, like `Name() string` or
+// `GetName() (string, error)`, to fields
+func (g *generator) interfaceFields(qt queuedType) error {
+	for _, entry := range parser.MethodSet(qt.ref) {
+		if !isGetter(entry.Func) {
+			g.warn(qt.t.Name, entry.Name, "only methods without parameters that return a value are converted")
+			continue
+		}
+
+		ft, err := g.typeRef(entry.Func.Results[0].Type)
+		if err != nil {
+			if unsupported, ok := err.(*unsupportedError); ok {
+				g.warn(qt.t.Name, entry.Name, unsupported.reason)
+				continue
+			}
+			return fmt.Errorf("unable to convert method %s [err=%v]", entry.Name, err)
+		}
+
+		qt.t.Fields = append(qt.t.Fields, &Field{
+			Name: getterFieldName(entry.Name),
+			Type: ft,
+		})
+	}
+
+	// Done
+	return nil
+}
+
+// addImplementations queues the structs of the package set that implement an interface
+func (g *generator) addImplementations(qt queuedType) {
+	if g.opts.Packages == nil {
+		return
+	}
+
+	importPaths := make([]string, 0, len(g.opts.Packages.Packages))
+	for importPath := range g.opts.Packages.Packages {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+
+	for _, importPath := range importPaths {
+		for _, impl := range parser.FindImplementations(g.opts.Packages.Packages[importPath], qt.ref) {
+			if parser.IsStruct(impl.Declaration.Underlying()) {
+				_, _ = g.namedType(impl.Declaration, impl.Declaration.Name, &parser.ParsedNonNativeType{
+					Name: impl.Declaration.Name,
+					Ref:  impl.Declaration,
+				})
+			}
+		}
+	}
+}
+
+// linkInterfaces sets the interfaces implemented by each object type and adds the interface
+// fields the object does not declare
+func (g *generator) linkInterfaces() {
+	for _, obj := range g.objects {
+		for _, iface := range g.ifaces {
+			ok, _ := parser.Implements(obj.ref, iface.ref)
+			if !ok {
+				ok, _ = parser.Implements(&parser.ParsedPointer{ToType: obj.ref}, iface.ref)
+			}
+			if !ok {
+				continue
+			}
+
+			obj.t.Interfaces = append(obj.t.Interfaces, iface.t.Name)
+			for _, f := range iface.t.Fields {
+				found := false
+				for _, objField := range obj.t.Fields {
+					if objField.Name == f.Name {
+						found = true
+						break
+					}
+				}
+				if !found {
+					obj.t.Fields = append(obj.t.Fields, f)
+				}
+			}
+		}
+		sort.Strings(obj.t.Interfaces)
+	}
+}
+
+// underlyingRef returns the type reference of a declaration represented by its underlying type,
+// like a list or a scalar. Declarations that contain themselves, like `type Tree []Tree`, cannot be
+// represented.
+func (g *generator) underlyingRef(pd *parser.ParsedDeclaration) (string, error) {
+	key := codegen.DeclarationKey(pd)
+	if _, ok := g.visiting[key]; ok {
+		return "", fmt.Errorf("invalid recursive type %s", pd.Name)
+	}
+	g.visiting[key] = struct{}{}
+	defer delete(g.visiting, key)
+
+	return g.typeRef(pd.Type)
+}
+
+// typeRef returns the GraphQL type reference of a Go type. Pointers are nullable, other types are
+// non-null.
+func (g *generator) typeRef(t parser.ParsedType) (string, error) {
+	switch tType := t.(type) {
+	case *parser.ParsedNativeType:
+		scalar, err := nativeScalar(tType.Name)
+		if err != nil {
+			return "", err
+		}
+		return scalar + "!", nil
+
+	case *parser.ParsedNonNativeType:
+		id := codegen.ID(tType)
+		if scalar, ok := wellKnownTypes[id]; ok {
+			return g.scalar(scalar) + "!", nil
+		}
+		if tType.Ref == nil {
+			if id == "any" || id == "error" {
+				return "", &unsupportedError{
+					reason: "empty interfaces cannot be represented in GraphQL",
+				}
+			}
+			return "", fmt.Errorf("unresolved reference to %s", tType.Name)
+		}
+
+		pd := tType.Ref
+		if pd.IsAlias {
+			return g.typeRef(pd.Type)
+		}
+		if len(pd.TypeParams) > 0 {
+			return "", fmt.Errorf("generic type %s must be instantiated", pd.Name)
+		}
+		name, err := g.namedType(pd, pd.Name, tType)
+		if err != nil {
+			return "", err
+		}
+		if len(name) == 0 {
+			return g.underlyingRef(pd)
+		}
+		return name + "!", nil
+
+	case *parser.ParsedIndex:
+		pd, err := parser.Instantiate(tType)
+		if err != nil {
+			return "", fmt.Errorf("unable to instantiate %s [err=%v]", parser.Render(tType, nil), err)
+		}
+		if generic, ok := tType.Type.(*parser.ParsedNonNativeType); ok && generic.Ref != nil {
+			pd.Doc = generic.Ref.Doc
+		}
+		name, err := g.namedType(pd, codegen.TypeName(pd.Name), tType)
+		if err != nil {
+			return "", err
+		}
+		if len(name) == 0 {
+			return g.underlyingRef(pd)
+		}
+		return name + "!", nil
+
+	case *parser.ParsedArray:
+		if len(tType.Size) == 0 && codegen.IsByte(tType.ValueType) {
+			return "String!", nil // Byte slices are encoded as base64 strings
+		}
+		item, err := g.typeRef(tType.ValueType)
+		if err != nil {
+			return "", err
+		}
+		return "[" + item + "]!", nil
+
+	case *parser.ParsedPointer:
+		ref, err := g.typeRef(tType.ToType)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(ref, "!"), nil
+
+	case *parser.ParsedMap:
+		return "", &unsupportedError{
+			reason: "maps cannot be represented in GraphQL",
+		}
+
+	case *parser.ParsedInterface:
+		return "", &unsupportedError{
+			reason: "anonymous interfaces cannot be represented in GraphQL",
+		}
+
+	case *parser.ParsedStruct:
+		return "", &unsupportedError{
+			reason: "anonymous structs cannot be represented in GraphQL",
+		}
+	}
+
+	return "", fmt.Errorf("type %s cannot be represented in GraphQL", parser.Render(t, nil))
+}
+
+func (g *generator) warn(typeName string, name string, reason string) {
+	g.schema.Warnings = append(g.schema.Warnings, fmt.Sprintf("%s.%s skipped: %s", typeName, name, reason))
+}
+
+// -----------------------------------------------------------------------------
+
+func (e *unsupportedError) Error() string {
+	return e.reason
+}
+
+func nativeScalar(name string) (string, error) {
+	switch name {
+	case "bool":
+		return "Boolean", nil
+	case "string":
+		return "String", nil
+	case "int", "int8", "int16", "int32", "int64", "rune", "uint", "uint8", "uint16", "uint32", "uint64",
+		"uintptr", "byte":
+		return "Int", nil
+	case "float32", "float64":
+		return "Float", nil
+	}
+	return "", fmt.Errorf("type %s cannot be represented in GraphQL", name)
+}
+
+// enumValues converts the names of the constants of an enum to GraphQL enum values, for example,
+// StatusPending becomes STATUS_PENDING
+func enumValues(constants []*parser.ParsedConstant) []string {
+	values := make([]string, 0, len(constants))
+	for _, pc := range constants {
+		if pc.Name == "_" {
+			continue
+		}
+		values = append(values, strings.ToUpper(codegen.SnakeCase(pc.Name)))
+	}
+	return values
+}
+
+func isGetter(pf *parser.ParsedFunction) bool {
+	if len(pf.Params) > 0 || len(pf.TypeParams) > 0 {
+		return false
+	}
+	switch len(pf.Results) {
+	case 1:
+		return true
+	case 2:
+		pnnt, ok := pf.Results[1].Type.(*parser.ParsedNonNativeType)
+		return ok && pnnt.Name == "error" && len(pnnt.ImportPath) == 0
+	}
+	return false
+}
+
+// getterFieldName converts a getter name, like GetName or Name, to a field name
+func getterFieldName(name string) string {
+	if strings.HasPrefix(name, "Get") && len(name) > 3 && unicode.IsUpper(rune(name[3])) {
+		name = name[3:]
+	}
+	return lowerCamelCase(name)
+}
+
+// replaceNamedType replaces the named type of a type reference keeping the list and non-null
+// modifiers
+func replaceNamedType(ref string, name string) string {
+	start := strings.LastIndex(ref, "[") + 1
+	end := start
+	for end < len(ref) && ref[end] != '!' && ref[end] != ']' {
+		end += 1
+	}
+	return ref[:start] + name + ref[end:]
+}
+
+// lowerCamelCase converts a Go identifier to the lowerCamelCase style used by GraphQL fields, for
+// example, ID becomes id and URLPath becomes urlPath
+func lowerCamelCase(name string) string {
+	runes := []rune(name)
+	for idx := 0; idx < len(runes) && unicode.IsUpper(runes[idx]); idx++ {
+		if idx > 0 && idx+1 < len(runes) && unicode.IsLower(runes[idx+1]) {
+			break
+		}
+		runes[idx] = unicode.ToLower(runes[idx])
+	}
+	return string(runes)
+}
+
+func writeDescription(sb *strings.Builder, description string, indent string) {
+	if len(description) == 0 {
+		return
+	}
+	if !strings.Contains(description, "\n") {
+		data, _ := json.Marshal(description)
+		sb.WriteString(indent + string(data) + "\n")
+		return
+	}
+	sb.WriteString(indent + "\"\"\"\n")
+	for _, line := range strings.Split(strings.ReplaceAll(description, "\"\"\"", "\\\"\"\""), "\n") {
+		sb.WriteString(strings.TrimRight(indent+line, " ") + "\n")
+	}
+	sb.WriteString(indent + "\"\"\"\n")
+}
@@ -0,0 +1,340 @@
+package graphql_test
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/graphql"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"time"
+)
+
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusShipped
+)
+
+// Node is an object with an identifier
+type Node interface {
+	GetID() string
+}
+
+// Order is a purchase order
+type Order struct {
+	ID       string
+	Status   Status
+	Customer *Customer
+	Lines    []*Line           ` + "`json:\"lines\"`" + `
+	Created  time.Time
+	Note     string            ` + "`graphql:\",nullable\"`" + ` // Optional note
+	Labels   map[string]string
+	Internal string            ` + "`graphql:\"-\"`" + `
+}
+
+func (o *Order) GetID() string {
+	return o.ID
+}
+
+type Customer struct {
+	Name string
+}
+
+func (c Customer) GetID() string {
+	return c.Name
+}
+
+type Line struct {
+	SKU string ` + "`graphql:\",type=ID\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	schema, err := graphql.Generate([]*parser.ParsedDeclaration{
+		ps.Lookup("github.com/mxmauro/gofile-parser-test", "Order"),
+		ps.Lookup("github.com/mxmauro/gofile-parser-test", "Node"),
+	}, graphql.Options{
+		Packages: ps,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	expected := `"Order is a purchase order"
+type Order implements Node {
+  id: String!
+  status: Status!
+  customer: Customer
+  lines: [Line]!
+  created: Time!
+  "Optional note"
+  note: String
+}
+
+"Node is an object with an identifier"
+interface Node {
+  id: String!
+}
+
+enum Status {
+  STATUS_PENDING
+  STATUS_SHIPPED
+}
+
+type Customer implements Node {
+  name: String!
+  id: String!
+}
+
+type Line {
+  sku: ID!
+}
+
+scalar Time
+`
+	if schema.String() != expected {
+		t.Fatalf("wrong schema:\n%v", schema.String())
+	}
+
+	if len(schema.Warnings) != 1 || !strings.HasPrefix(schema.Warnings[0], "Order.Labels skipped") {
+		t.Fatalf("wrong warnings: %v", schema.Warnings)
+	}
+}
+
+func TestNameCollision(t *testing.T) {
+	common, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package common
+
+type User struct {
+	Email string
+}
+`,
+		Filename: "common.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "common",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+type User struct {
+	Name    string
+	Account common.User
+	Friend  *User
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{common, pf})
+	ps.ResolveReferences()
+
+	schema, err := graphql.Generate([]*parser.ParsedDeclaration{
+		ps.Lookup("github.com/mxmauro/gofile-parser-test", "User"),
+	}, graphql.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	expected := `type User {
+  name: String!
+  account: User2!
+  friend: User
+}
+
+type User2 {
+  email: String!
+}
+`
+	if schema.String() != expected {
+		t.Fatalf("wrong schema:\n%v", schema.String())
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Comments []Comment
+
+type Comment struct {
+	Text    string
+	Replies Comments
+	Parent  *Comment
+	Thread  *Page[Comment]
+}
+
+type Page[T any] struct {
+	Items []T
+	Next  *string
+}
+
+type Tree []Tree
+
+type Nodes[T any] []Nodes[T]
+
+type Forest struct {
+	Trees Tree
+}
+
+type Graph struct {
+	Nodes Nodes[int]
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	// Lists of a recursive object are referenced by the object name
+	schema, err := graphql.Generate([]*parser.ParsedDeclaration{&pf.Declarations[1]}, graphql.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	expected := `type Comment {
+  text: String!
+  replies: [Comment!]!
+  parent: Comment
+  thread: Page_Comment
+}
+
+type Page_Comment {
+  items: [Comment!]!
+  next: String
+}
+`
+	if schema.String() != expected {
+		t.Fatalf("wrong schema:\n%v", schema.String())
+	}
+
+	// Lists that contain themselves have no GraphQL representation
+	for _, tc := range []struct {
+		pd       *parser.ParsedDeclaration
+		expected string
+	}{
+		{&pf.Declarations[5], "unable to convert Forest [err=unable to convert field Trees [err=invalid recursive type Tree]]"},
+		{&pf.Declarations[6], "unable to convert Graph [err=unable to convert field Nodes [err=invalid recursive type Nodes[int]]]"},
+	} {
+		_, err = graphql.Generate([]*parser.ParsedDeclaration{tc.pd}, graphql.Options{})
+		if err == nil || err.Error() != tc.expected {
+			t.Fatalf("wrong error for %v: %v", tc.pd.Name, err)
+		}
+	}
+}
+
+func TestSkippedFields(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Profile struct {
+	Name     string
+	Settings map[string]string
+	Extra    any
+	Address  struct {
+		City string
+	}
+	Owner Owner
+}
+
+type Owner interface {
+	Name() string
+	Permissions(scope string) []string
+	Metadata() interface{ Get() string }
+}
+
+type Shape interface {
+	Area() complex128
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	schema, err := graphql.Generate([]*parser.ParsedDeclaration{&pf.Declarations[0]}, graphql.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	expected := `type Profile {
+  name: String!
+  owner: Owner!
+}
+
+interface Owner {
+  name: String!
+}
+`
+	if schema.String() != expected {
+		t.Fatalf("wrong schema:\n%v", schema.String())
+	}
+
+	// Fields that cannot be represented are reported instead of failing
+	warnings := []string{
+		"Profile.Settings skipped: maps cannot be represented in GraphQL",
+		"Profile.Extra skipped: empty interfaces cannot be represented in GraphQL",
+		"Profile.Address skipped: anonymous structs cannot be represented in GraphQL",
+		"Owner.Metadata skipped: anonymous interfaces cannot be represented in GraphQL",
+		"Owner.Permissions skipped: only methods without parameters that return a value are converted",
+	}
+	if strings.Join(schema.Warnings, "\n") != strings.Join(warnings, "\n") {
+		t.Fatalf("wrong warnings:\n%v", strings.Join(schema.Warnings, "\n"))
+	}
+
+	// Types without a GraphQL scalar are not skipped
+	_, err = graphql.Generate([]*parser.ParsedDeclaration{&pf.Declarations[2]}, graphql.Options{})
+	if err == nil || err.Error() != "unable to convert Shape [err=unable to convert method Area [err=type complex128 cannot be represented in GraphQL]]" {
+		t.Fatalf("wrong error for Shape: %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+package parser
+
+import (
+	"go/ast"
+)
+
+// -----------------------------------------------------------------------------
+
+type IdenticalOptions struct {
+	// IgnoreNames compares named types using their underlying types instead of their declarations,
+	// so two different declarations with the same structure are considered identical.
+	IgnoreNames bool
+}
+
+type identityComparer struct {
+	opts    IdenticalOptions
+	assumed map[[2]interface{}]struct{}
+}
+
+type expandedField struct {
+	name     string
+	embedded bool
+	t        ParsedType
+	tag      string
+}
+
+// -----------------------------------------------------------------------------
+
+// Identical returns true if both types are identical following Go's type identity rules: named
+// types are identical if they refer to the same declaration, structs must have the same fields,
+// embedded-ness and tags in the same order, and function signatures ignore parameter names.
+// Aliases are followed before comparing.
+func Identical(a, b ParsedType) bool {
+	return IdenticalWithOptions(a, b, IdenticalOptions{})
+}
+
+// IdenticalWithOptions works like Identical but allows to customize the comparison
+func IdenticalWithOptions(a, b ParsedType, opts IdenticalOptions) bool {
+	ic := identityComparer{
+		opts:    opts,
+		assumed: make(map[[2]interface{}]struct{}),
+	}
+	return ic.identical(a, b)
+}
+
+// AssignableTo returns true if a value of type v can be assigned to a variable of type t. It
+// applies identity, identical underlying types when one of them is not named, channel direction
+// and interface satisfaction rules.
+func AssignableTo(v, t ParsedType) bool {
+	if Identical(v, t) {
+		return true
+	}
+
+	vu := Underlying(Unalias(v))
+	tu := Underlying(Unalias(t))
+	if vu == nil || tu == nil {
+		return false
+	}
+
+	// Any type is assignable to an empty interface
+	if pi, ok := tu.(*ParsedInterface); ok && len(pi.Methods) == 0 {
+		return true
+	}
+	if pnnt, ok := tu.(*ParsedNonNativeType); ok && pnnt.Ref == nil && pnnt.Name == "any" {
+		return true
+	}
+	if _, ok := tu.(*ParsedInterface); ok {
+		implements, _ := Implements(v, t)
+		return implements
+	}
+
+	if isNamedType(Unalias(v)) && isNamedType(Unalias(t)) {
+		return false
+	}
+	if Identical(vu, tu) {
+		return true
+	}
+
+	// A bidirectional channel is assignable to a directional one with the same element type
+	vc, ok1 := vu.(*ParsedChannel)
+	tc, ok2 := tu.(*ParsedChannel)
+	return ok1 && ok2 && vc.Dir == ast.SEND|ast.RECV && Identical(vc.Type, tc.Type)
+}
+
+func (ic *identityComparer) identical(a, b ParsedType) bool {
+	a = Unalias(a)
+	b = Unalias(b)
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a == b {
+		return true
+	}
+
+	if ic.opts.IgnoreNames && (isDeclaredType(a) || isDeclaredType(b)) {
+		// Assume both types are identical while comparing them, this avoids infinite recursion on
+		// recursive types
+		key := [2]interface{}{identityKey(a), identityKey(b)}
+		if _, ok := ic.assumed[key]; ok {
+			return true
+		}
+		ic.assumed[key] = struct{}{}
+
+		// Invalid declarations, like cyclic ones, are not identical to anything
+		aUnderlying := Underlying(a)
+		bUnderlying := Underlying(b)
+		if aUnderlying == nil || bUnderlying == nil {
+			return false
+		}
+		return ic.identical(aUnderlying, bUnderlying)
+	}
+
+	switch aType := a.(type) {
+	case *ParsedNativeType:
+		bType, ok := b.(*ParsedNativeType)
+		return ok && canonicalNativeName(aType.Name) == canonicalNativeName(bType.Name)
+
+	case *ParsedNonNativeType, *ParsedTypeParamRef:
+		return ic.identicalNamed(a, b)
+
+	case *ParsedStruct:
+		bType, ok := b.(*ParsedStruct)
+		if !ok {
+			return false
+		}
+		aFields := expandFields(aType.Fields)
+		bFields := expandFields(bType.Fields)
+		if len(aFields) != len(bFields) {
+			return false
+		}
+		for idx := range aFields {
+			if aFields[idx].name != bFields[idx].name || aFields[idx].embedded != bFields[idx].embedded ||
+				aFields[idx].tag != bFields[idx].tag || (!ic.identical(aFields[idx].t, bFields[idx].t)) {
+				return false
+			}
+		}
+		return true
+
+	case *ParsedInterface:
+		bType, ok := b.(*ParsedInterface)
+		return ok && ic.identicalInterfaces(aType, bType)
+
+	case *ParsedMap:
+		bType, ok := b.(*ParsedMap)
+		return ok && ic.identical(aType.KeyType, bType.KeyType) && ic.identical(aType.ValueType, bType.ValueType)
+
+	case *ParsedArray:
+		bType, ok := b.(*ParsedArray)
+		if !ok || (len(aType.Size) == 0) != (len(bType.Size) == 0) {
+			return false
+		}
+		if len(aType.Size) > 0 {
+			if aType.ParsedInt != nil && bType.ParsedInt != nil {
+				if *aType.ParsedInt != *bType.ParsedInt {
+					return false
+				}
+			} else if aType.Size != bType.Size {
+				return false
+			}
+		}
+		return ic.identical(aType.ValueType, bType.ValueType)
+
+	case *ParsedPointer:
+		bType, ok := b.(*ParsedPointer)
+		return ok && ic.identical(aType.ToType, bType.ToType)
+
+	case *ParsedChannel:
+		bType, ok := b.(*ParsedChannel)
+		return ok && aType.Dir == bType.Dir && ic.identical(aType.Type, bType.Type)
+
+	case *ParsedFunction:
+		bType, ok := b.(*ParsedFunction)
+		return ok && ic.identicalSignatures(aType, bType)
+
+	case *ParsedIndex:
+		bType, ok := b.(*ParsedIndex)
+		if !ok || len(aType.Indexes) != len(bType.Indexes) || (!ic.identical(aType.Type, bType.Type)) {
+			return false
+		}
+		for idx := range aType.Indexes {
+			if !ic.identical(aType.Indexes[idx], bType.Indexes[idx]) {
+				return false
+			}
+		}
+		return true
+
+	case *ParsedUnion:
+		bType, ok := b.(*ParsedUnion)
+		if !ok || len(aType.Terms) != len(bType.Terms) {
+			return false
+		}
+		for idx := range aType.Terms {
+			if aType.Terms[idx].Tilde != bType.Terms[idx].Tilde || (!ic.identical(aType.Terms[idx].Type, bType.Terms[idx].Type)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (ic *identityComparer) identicalNamed(a, b ParsedType) bool {
+	switch aType := a.(type) {
+	case *ParsedNonNativeType:
+		bType, ok := b.(*ParsedNonNativeType)
+		if !ok {
+			return false
+		}
+		if aType.Ref != nil || bType.Ref != nil {
+			if aType.Ref != nil && bType.Ref != nil && aType.Ref.instanceOf != nil && bType.Ref.instanceOf != nil {
+				// Each instantiation creates a new declaration, compare the generic and the arguments
+				return aType.Ref == bType.Ref || ic.identical(aType.Ref.instanceOf, bType.Ref.instanceOf)
+			}
+			return aType.Ref == bType.Ref
+		}
+		if len(aType.ImportPath) > 0 || len(bType.ImportPath) > 0 {
+			// The same package can be imported with different names in each file
+			_, aName := GetIdentifierParts(aType.Name)
+			_, bName := GetIdentifierParts(bType.Name)
+			return aName == bName && aType.ImportPath == bType.ImportPath
+		}
+		return aType.Name == bType.Name
+
+	case *ParsedTypeParamRef:
+		bType, ok := b.(*ParsedTypeParamRef)
+		return ok && aType.Param == bType.Param && aType.Name == bType.Name
+	}
+	return false
+}
+
+func (ic *identityComparer) identicalSignatures(a, b *ParsedFunction) bool {
+	if a.Variadic != b.Variadic {
+		return false
+	}
+	return ic.identicalFieldTypes(a.TypeParams, b.TypeParams) &&
+		ic.identicalFieldTypes(a.Params, b.Params) &&
+		ic.identicalFieldTypes(a.Results, b.Results)
+}
+
+func (ic *identityComparer) identicalFieldTypes(a, b []ParsedField) bool {
+	aFields := expandFields(a)
+	bFields := expandFields(b)
+	if len(aFields) != len(bFields) {
+		return false
+	}
+	for idx := range aFields {
+		if !ic.identical(aFields[idx].t, bFields[idx].t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ic *identityComparer) identicalInterfaces(a, b *ParsedInterface) bool {
+	aMethods, aEmbedded := splitInterfaceMembers(a)
+	bMethods, bEmbedded := splitInterfaceMembers(b)
+	if len(aMethods) != len(bMethods) || len(aEmbedded) != len(bEmbedded) {
+		return false
+	}
+
+	// Methods are sorted by name, so they are compared regardless of their declaration order
+	for idx := range aMethods {
+		if aMethods[idx].name != bMethods[idx].name || (!ic.identical(aMethods[idx].t, bMethods[idx].t)) {
+			return false
+		}
+	}
+	for idx := range aEmbedded {
+		if !ic.identical(aEmbedded[idx].t, bEmbedded[idx].t) {
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
+
+// expandFields converts a field list where several names can share the same type, like
+// `A, B int`, into a list with one entry per name
+func expandFields(fields []ParsedField) []expandedField {
+	expanded := make([]expandedField, 0, len(fields))
+	for _, field := range fields {
+		if len(field.Names) == 0 {
+			expanded = append(expanded, expandedField{
+				name:     field.ImplicitName,
+				embedded: true,
+				t:        field.Type,
+				tag:      field.rawTag,
+			})
+			continue
+		}
+		for _, name := range field.Names {
+			expanded = append(expanded, expandedField{
+				name: name,
+				t:    field.Type,
+				tag:  field.rawTag,
+			})
+		}
+	}
+	return expanded
+}
+
+// splitInterfaceMembers returns the methods of an interface, including the ones of embedded
+// interfaces, and the embedded elements that cannot be expanded, like unresolved interfaces or
+// type unions
+func splitInterfaceMembers(pi *ParsedInterface) ([]expandedField, []expandedField) {
+	methods := make([]expandedField, 0)
+	for _, entry := range interfaceMethodSet(pi, make(map[*ParsedInterface]struct{})) {
+		methods = append(methods, expandedField{
+			name: entry.Name,
+			t:    entry.Func,
+		})
+	}
+
+	embedded := make([]expandedField, 0)
+	for _, member := range expandFields(pi.Methods) {
+		if member.embedded {
+			if _, ok := Underlying(Unalias(member.t)).(*ParsedInterface); !ok {
+				embedded = append(embedded, member)
+			}
+		}
+	}
+	return methods, embedded
+}
+
+func isNamedType(t ParsedType) bool {
+	switch t.(type) {
+	case *ParsedNativeType, *ParsedNonNativeType, *ParsedTypeParamRef, *ParsedIndex:
+		return true
+	}
+	return false
+}
+
+// isDeclaredType returns true for types defined by a resolved declaration
+func isDeclaredType(t ParsedType) bool {
+	switch tType := t.(type) {
+	case *ParsedNonNativeType:
+		return tType.Ref != nil
+	case *ParsedIndex:
+		return true
+	}
+	return false
+}
+
+func identityKey(t ParsedType) interface{} {
+	if pnnt, ok := t.(*ParsedNonNativeType); ok && pnnt.Ref != nil {
+		return pnnt.Ref
+	}
+	return t
+}
+
+func canonicalNativeName(name string) string {
+	switch name {
+	case "byte":
+		return "uint8"
+	case "rune":
+		return "int32"
+	}
+	return name
+}
@@ -0,0 +1,126 @@
+package codegen
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	parser "github.com/mxmauro/gofile-parser"
+)
+
+// -----------------------------------------------------------------------------
+
+// ID returns the qualified name of the declaration a type name refers to, like `time.Time` or
+// `github.com/google/uuid.UUID`, whether it was parsed or not. Generators use it to map well-known
+// types. The import path is omitted for unresolved names of the same package and for predeclared
+// ones like `any`.
+func ID(pnnt *parser.ParsedNonNativeType) string {
+	if pnnt.Ref != nil {
+		if pf := pnnt.Ref.File(); pf != nil {
+			return pf.Module.FullName() + "." + pnnt.Ref.Name
+		}
+		return pnnt.Ref.Name
+	}
+
+	_, name := parser.GetIdentifierParts(pnnt.Name)
+	if len(pnnt.ImportPath) > 0 {
+		return pnnt.ImportPath + "." + name
+	}
+	return name
+}
+
+// DeclarationKey returns a key that identifies a declaration, including instances of generic
+// types which are created on each instantiation
+func DeclarationKey(pd *parser.ParsedDeclaration) string {
+	if pf := pd.File(); pf != nil {
+		return pf.Module.FullName() + "." + pd.Name
+	}
+	return fmt.Sprintf("%p", pd)
+}
+
+// Marshaler returns the name of the method encoding/json uses to encode the type, MarshalJSON or
+// MarshalText, or an empty string if it is encoded by its fields. Methods of both the type and a
+// pointer to it are checked.
+func Marshaler(t parser.ParsedType) string {
+	// Entries are sorted by name, so MarshalJSON takes precedence like in encoding/json
+	for _, entry := range parser.MethodSet(&parser.ParsedPointer{ToType: t}) {
+		switch entry.Name {
+		case "MarshalJSON", "MarshalText":
+			return entry.Name
+		}
+	}
+	return ""
+}
+
+// IsByte returns true if the underlying type is byte or uint8
+func IsByte(t parser.ParsedType) bool {
+	pnt, ok := parser.Underlying(t).(*parser.ParsedNativeType)
+	return ok && (pnt.Name == "byte" || pnt.Name == "uint8")
+}
+
+// IsStringable returns true if the type can be encoded as a JSON string with the `string` option
+// of the json tag
+func IsStringable(t parser.ParsedType) bool {
+	if pp, ok := parser.Unalias(t).(*parser.ParsedPointer); ok {
+		t = pp.ToType
+	}
+	return parser.IsNumeric(t) || parser.IsBool(t) || parser.IsString(t)
+}
+
+// SplitTag returns the name, the first element of a tag, and the rest of the options. GetProperty
+// does not accept an empty first element, like in `,omitempty`, so the options are returned as a
+// separate tag.
+func SplitTag(tag parser.ParsedTag) (string, parser.ParsedTag) {
+	s := string(tag)
+	idx := strings.IndexByte(s, ',')
+	if idx < 0 {
+		return strings.TrimSpace(s), ""
+	}
+	return strings.TrimSpace(s[:idx]), parser.ParsedTag(s[idx+1:])
+}
+
+// TypeName converts a type name, like List[pkg.User], to a plain identifier like List_pkg_User
+func TypeName(name string) string {
+	r := strings.NewReplacer("[", "_", "]", "", ",", "_", " ", "", ".", "_", "*", "")
+	return r.Replace(name)
+}
+
+// SnakeCase converts a Go identifier to snake case, keeping acronyms together, like UserID to
+// user_id
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	sb := strings.Builder{}
+	for idx, r := range runes {
+		if unicode.IsUpper(r) {
+			if idx > 0 && (unicode.IsLower(runes[idx-1]) || unicode.IsDigit(runes[idx-1]) ||
+				(idx+1 < len(runes) && unicode.IsLower(runes[idx+1]) && unicode.IsUpper(runes[idx-1]))) {
+				sb.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// RelativePath returns the path of a file relative to the directory of another one. Both paths
+// must be relative to the same root.
+func RelativePath(from string, to string) string {
+	fromParts := strings.Split(path.Dir(from), "/")
+	toParts := strings.Split(to, "/")
+
+	common := 0
+	for common < len(fromParts) && common < len(toParts)-1 && fromParts[common] == toParts[common] {
+		common += 1
+	}
+
+	parts := make([]string, 0)
+	for idx := common; idx < len(fromParts); idx++ {
+		if fromParts[idx] != "." {
+			parts = append(parts, "..")
+		}
+	}
+	parts = append(parts, toParts[common:]...)
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,122 @@
+package codegen_test
+
+import (
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+//------------------------------------------------------------------------------
+
+func TestID(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"time"
+
+	uuidpkg "github.com/google/uuid"
+)
+
+type User struct {
+	ID      uuidpkg.UUID
+	Created time.Time
+	Parent  *User
+	Extra   any
+}
+
+func (u User) MarshalText() ([]byte, error) {
+	return nil, nil
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	user := ps.Lookup("github.com/mxmauro/gofile-parser-test", "User")
+	ps2 := user.Type.(*parser.ParsedStruct)
+
+	expected := []string{
+		"github.com/google/uuid.UUID",
+		"time.Time",
+		"github.com/mxmauro/gofile-parser-test.User",
+		"any",
+	}
+	for idx, field := range ps2.Fields {
+		if pp, ok := field.Type.(*parser.ParsedPointer); ok {
+			field.Type = pp.ToType
+		}
+		id := codegen.ID(field.Type.(*parser.ParsedNonNativeType))
+		if id != expected[idx] {
+			t.Fatalf("wrong id %v for field %v", id, field.Names[0])
+		}
+	}
+
+	if m := codegen.Marshaler(ps2.Fields[2].Type.(*parser.ParsedPointer).ToType); m != "MarshalText" {
+		t.Fatalf("wrong marshaler %v", m)
+	}
+	if m := codegen.Marshaler(ps2.Fields[3].Type); m != "" {
+		t.Fatalf("wrong marshaler %v", m)
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	if s := codegen.TypeName("Page[pkg.User, *Line]"); s != "Page_pkg_User_Line" {
+		t.Fatalf("wrong type name %v", s)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"Line2Text":  "line2_text",
+		"name":       "name",
+	} {
+		if s := codegen.SnakeCase(name); s != expected {
+			t.Fatalf("wrong snake case %v for %v", s, name)
+		}
+	}
+}
+
+func TestSplitTag(t *testing.T) {
+	name, props := codegen.SplitTag(",omitempty,type=ID")
+	if name != "" || (!props.HasProperty("omitempty")) {
+		t.Fatalf("wrong split tag")
+	}
+	if value, _ := props.GetProperty("type"); value != "ID" {
+		t.Fatalf("wrong property value %v", value)
+	}
+
+	name, props = codegen.SplitTag("id")
+	if name != "id" || len(props) != 0 {
+		t.Fatalf("wrong split tag")
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	for _, tc := range []struct {
+		from     string
+		to       string
+		expected string
+	}{
+		{"a/b/file.md", "a/b/other.md", "other.md"},
+		{"a/b/file.md", "a/c/other.md", "../c/other.md"},
+		{"a/file.md", "a/b/c/other.md", "b/c/other.md"},
+		{"file.md", "a/other.md", "a/other.md"},
+	} {
+		if s := codegen.RelativePath(tc.from, tc.to); s != tc.expected {
+			t.Fatalf("wrong relative path %v from %v to %v", s, tc.from, tc.to)
+		}
+	}
+}
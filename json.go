@@ -0,0 +1,854 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	goparser "go/parser"
+	"go/token"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+type jsonPackageSet struct {
+	Files []*ParsedFile `json:"files"`
+}
+
+type jsonFile struct {
+	Module       jsonModule        `json:"module"`
+	Filename     string            `json:"filename"`
+	Package      string            `json:"package"`
+	Imports      []jsonImport      `json:"imports"`
+	Declarations []jsonDeclaration `json:"declarations"`
+	Constants    []jsonConstant    `json:"constants"`
+	Methods      []jsonMethod      `json:"methods"`
+}
+
+type jsonModule struct {
+	Name   string `json:"name"`
+	SubDir string `json:"subDir,omitempty"`
+}
+
+type jsonImport struct {
+	Name         string `json:"name,omitempty"`
+	ImplicitName string `json:"implicitName"`
+	Path         string `json:"path"`
+}
+
+type jsonDeclaration struct {
+	Name       string      `json:"name"`
+	TypeParams []jsonField `json:"typeParams,omitempty"`
+	Type       *jsonType   `json:"type"`
+	Tags       ParsedTags  `json:"tags,omitempty"`
+	IsAlias    bool        `json:"isAlias,omitempty"`
+	Doc        string      `json:"doc,omitempty"`
+}
+
+type jsonConstant struct {
+	Name        string             `json:"name"`
+	Type        *jsonType          `json:"type,omitempty"`
+	Value       string             `json:"value"`
+	Iota        int                `json:"iota"`
+	ParsedValue *jsonConstantValue `json:"parsedValue,omitempty"`
+}
+
+type jsonConstantValue struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+	Imag  string `json:"imag,omitempty"`
+}
+
+type jsonMethod struct {
+	Name               string      `json:"name"`
+	Receiver           string      `json:"receiver"`
+	PointerReceiver    bool        `json:"pointerReceiver,omitempty"`
+	ReceiverTypeParams []jsonField `json:"receiverTypeParams,omitempty"`
+	Type               *jsonType   `json:"type"`
+}
+
+type jsonField struct {
+	Names        []string   `json:"names,omitempty"`
+	ImplicitName string     `json:"implicitName,omitempty"`
+	Type         *jsonType  `json:"type,omitempty"`
+	Tags         ParsedTags `json:"tags,omitempty"`
+	RawTag       string     `json:"rawTag,omitempty"`
+	Doc          string     `json:"doc,omitempty"`
+}
+
+type jsonType struct {
+	Kind       string          `json:"kind"`
+	Name       string          `json:"name,omitempty"`
+	ImportPath string          `json:"importPath,omitempty"`
+	Ref        string          `json:"ref,omitempty"`
+	Instance   *jsonType       `json:"instance,omitempty"`
+	Fields     []jsonField     `json:"fields,omitempty"`
+	Incomplete bool            `json:"incomplete,omitempty"`
+	Key        *jsonType       `json:"key,omitempty"`
+	Elem       *jsonType       `json:"elem,omitempty"`
+	Size       string          `json:"size,omitempty"`
+	Length     *int64          `json:"length,omitempty"`
+	SizeError  string          `json:"sizeError,omitempty"`
+	Dir        string          `json:"dir,omitempty"`
+	TypeParams []jsonField     `json:"typeParams,omitempty"`
+	Params     []jsonField     `json:"params,omitempty"`
+	Results    []jsonField     `json:"results,omitempty"`
+	Variadic   bool            `json:"variadic,omitempty"`
+	Indexes    []*jsonType     `json:"indexes,omitempty"`
+	Terms      []jsonUnionTerm `json:"terms,omitempty"`
+}
+
+type jsonUnionTerm struct {
+	Tilde bool      `json:"tilde,omitempty"`
+	Type  *jsonType `json:"type"`
+}
+
+type jsonDecoder struct {
+	scopes    []map[string]*ParsedField
+	pending   []pendingRef
+	instances []pendingInstance
+	exprs     *exprParser
+}
+
+// exprParser re-creates the array length and constant expressions of a decoded file from their
+// source text, so ResolveReferences can evaluate them. The texts are concatenated in the same
+// order as the positions assigned by the file set, so error messages can quote them.
+type exprParser struct {
+	fset    *token.FileSet
+	content strings.Builder
+}
+
+type pendingRef struct {
+	pnnt *ParsedNonNativeType
+	id   string
+}
+
+type pendingInstance struct {
+	pnnt *ParsedNonNativeType
+	pi   *ParsedIndex
+}
+
+// -----------------------------------------------------------------------------
+
+// MarshalJSON encodes the parsed file. Each type node contains a `kind` discriminator and resolved
+// references are encoded as qualified identifiers, like `github.com/acme/app/models.User`.
+// References to instances of generic types also contain the index expression they were
+// instantiated from.
+func (pf *ParsedFile) MarshalJSON() ([]byte, error) {
+	jf := jsonFile{
+		Module: jsonModule{
+			Name:   pf.Module.Name,
+			SubDir: pf.Module.SubDir,
+		},
+		Filename:     pf.Filename,
+		Package:      pf.Package,
+		Imports:      make([]jsonImport, 0, len(pf.Imports)),
+		Declarations: make([]jsonDeclaration, 0, len(pf.Declarations)),
+		Constants:    make([]jsonConstant, 0, len(pf.Constants)),
+		Methods:      make([]jsonMethod, 0, len(pf.Methods)),
+	}
+	for _, pi := range pf.Imports {
+		jf.Imports = append(jf.Imports, jsonImport{
+			Name:         pi.Name,
+			ImplicitName: pi.ImplicitName,
+			Path:         pi.Path,
+		})
+	}
+
+	for pdIdx := range pf.Declarations {
+		pd := &pf.Declarations[pdIdx]
+		jf.Declarations = append(jf.Declarations, jsonDeclaration{
+			Name:       pd.Name,
+			TypeParams: encodeFields(pd.TypeParams),
+			Type:       encodeType(pd.Type),
+			Tags:       pd.Tags,
+			IsAlias:    pd.IsAlias,
+			Doc:        pd.Doc,
+		})
+	}
+	for pcIdx := range pf.Constants {
+		pc := &pf.Constants[pcIdx]
+		jf.Constants = append(jf.Constants, jsonConstant{
+			Name:        pc.Name,
+			Type:        encodeType(pc.Type),
+			Value:       pc.Value,
+			Iota:        pc.Iota,
+			ParsedValue: encodeConstantValue(pc.ParsedValue),
+		})
+	}
+	for pmIdx := range pf.Methods {
+		pm := &pf.Methods[pmIdx]
+		jm := jsonMethod{
+			Name:               pm.Name,
+			Receiver:           pm.Receiver,
+			PointerReceiver:    pm.PointerReceiver,
+			ReceiverTypeParams: encodeFields(pm.ReceiverTypeParams),
+		}
+		if pm.Type != nil {
+			jm.Type = encodeType(pm.Type)
+		}
+		jf.Methods = append(jf.Methods, jm)
+	}
+
+	// Done
+	return json.Marshal(jf)
+}
+
+// UnmarshalJSON decodes a parsed file encoded with MarshalJSON. References to declarations of the
+// same file and methods are re-linked. References to other files are only re-linked when files
+// are decoded as part of a PackageSet, else they can be resolved later with ResolveReferences,
+// except for references to instances of generic types declared in other files.
+func (pf *ParsedFile) UnmarshalJSON(data []byte) error {
+	dec := jsonDecoder{
+		scopes:    make([]map[string]*ParsedField, 0),
+		pending:   make([]pendingRef, 0),
+		instances: make([]pendingInstance, 0),
+	}
+	err := dec.decodeFile(data, pf)
+	if err != nil {
+		return err
+	}
+
+	ps := NewPackageSet([]*ParsedFile{pf})
+	err = dec.relink(ps)
+	if err != nil {
+		return err
+	}
+
+	// Done
+	return nil
+}
+
+// MarshalJSON encodes all the files of the package set
+func (ps *PackageSet) MarshalJSON() ([]byte, error) {
+	jps := jsonPackageSet{
+		Files: ps.files,
+	}
+	if jps.Files == nil {
+		jps.Files = make([]*ParsedFile, 0)
+	}
+	return json.Marshal(jps)
+}
+
+// UnmarshalJSON decodes a package set encoded with MarshalJSON and re-links the references between
+// all of its files
+func (ps *PackageSet) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Files []json.RawMessage `json:"files"`
+	}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	dec := jsonDecoder{
+		scopes:    make([]map[string]*ParsedField, 0),
+		pending:   make([]pendingRef, 0),
+		instances: make([]pendingInstance, 0),
+	}
+	parsedFiles := make([]*ParsedFile, 0, len(raw.Files))
+	for idx, rawFile := range raw.Files {
+		pf := &ParsedFile{}
+		err = dec.decodeFile(rawFile, pf)
+		if err != nil {
+			return fmt.Errorf("unable to decode file #%d [err=%v]", idx+1, err)
+		}
+		parsedFiles = append(parsedFiles, pf)
+	}
+
+	*ps = *NewPackageSet(parsedFiles)
+	err = dec.relink(ps)
+	if err != nil {
+		return err
+	}
+
+	// Done
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func encodeFields(fields []ParsedField) []jsonField {
+	if fields == nil {
+		return nil
+	}
+
+	jfields := make([]jsonField, 0, len(fields))
+	for _, field := range fields {
+		jfields = append(jfields, jsonField{
+			Names:        field.Names,
+			ImplicitName: field.ImplicitName,
+			Type:         encodeType(field.Type),
+			Tags:         field.Tags,
+			RawTag:       field.rawTag,
+			Doc:          field.Doc,
+		})
+	}
+	return jfields
+}
+
+func encodeType(t ParsedType) *jsonType {
+	if t == nil {
+		return nil
+	}
+
+	jt := &jsonType{
+		Kind: t.Kind().String(),
+	}
+
+	switch tType := t.(type) {
+	case *ParsedNativeType:
+		jt.Name = tType.Name
+
+	case *ParsedNonNativeType:
+		jt.Name = tType.Name
+		jt.ImportPath = tType.ImportPath
+		if tType.Ref != nil {
+			if tType.Ref.instanceOf != nil {
+				jt.Instance = encodeType(tType.Ref.instanceOf)
+			} else if tType.Ref.file != nil {
+				jt.Ref = tType.Ref.file.Module.FullName() + "." + tType.Ref.Name
+			}
+		}
+
+	case *ParsedTypeParamRef:
+		jt.Name = tType.Name
+
+	case *ParsedStruct:
+		jt.Fields = encodeFields(tType.Fields)
+
+	case *ParsedInterface:
+		jt.Fields = encodeFields(tType.Methods)
+		jt.Incomplete = tType.Incomplete
+
+	case *ParsedMap:
+		jt.Key = encodeType(tType.KeyType)
+		jt.Elem = encodeType(tType.ValueType)
+
+	case *ParsedArray:
+		jt.Size = tType.Size
+		jt.Length = tType.ParsedInt
+		if tType.SizeError != nil {
+			jt.SizeError = tType.SizeError.Error()
+		}
+		jt.Elem = encodeType(tType.ValueType)
+
+	case *ParsedPointer:
+		jt.Elem = encodeType(tType.ToType)
+
+	case *ParsedChannel:
+		switch tType.Dir {
+		case ast.SEND:
+			jt.Dir = "send"
+		case ast.RECV:
+			jt.Dir = "recv"
+		default:
+			jt.Dir = "both"
+		}
+		jt.Elem = encodeType(tType.Type)
+
+	case *ParsedFunction:
+		jt.TypeParams = encodeFields(tType.TypeParams)
+		jt.Params = encodeFields(tType.Params)
+		jt.Results = encodeFields(tType.Results)
+		jt.Variadic = tType.Variadic
+
+	case *ParsedIndex:
+		jt.Elem = encodeType(tType.Type)
+		jt.Indexes = make([]*jsonType, 0, len(tType.Indexes))
+		for _, idx := range tType.Indexes {
+			jt.Indexes = append(jt.Indexes, encodeType(idx))
+		}
+
+	case *ParsedUnion:
+		jt.Terms = make([]jsonUnionTerm, 0, len(tType.Terms))
+		for _, term := range tType.Terms {
+			jt.Terms = append(jt.Terms, jsonUnionTerm{
+				Tilde: term.Tilde,
+				Type:  encodeType(term.Type),
+			})
+		}
+	}
+
+	// Done
+	return jt
+}
+
+func encodeConstantValue(v constant.Value) *jsonConstantValue {
+	if v == nil {
+		return nil
+	}
+
+	switch v.Kind() {
+	case constant.Bool:
+		return &jsonConstantValue{
+			Kind:  "bool",
+			Value: v.ExactString(),
+		}
+	case constant.String:
+		return &jsonConstantValue{
+			Kind:  "string",
+			Value: constant.StringVal(v),
+		}
+	case constant.Int:
+		return &jsonConstantValue{
+			Kind:  "int",
+			Value: v.ExactString(),
+		}
+	case constant.Float:
+		return &jsonConstantValue{
+			Kind:  "float",
+			Value: v.ExactString(),
+		}
+	case constant.Complex:
+		return &jsonConstantValue{
+			Kind:  "complex",
+			Value: constant.Real(v).ExactString(),
+			Imag:  constant.Imag(v).ExactString(),
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (dec *jsonDecoder) decodeFile(data []byte, pf *ParsedFile) error {
+	var jf jsonFile
+
+	err := json.Unmarshal(data, &jf)
+	if err != nil {
+		return err
+	}
+
+	dec.exprs = &exprParser{
+		fset: token.NewFileSet(),
+	}
+
+	*pf = ParsedFile{
+		Module: Module{
+			Name:   jf.Module.Name,
+			SubDir: jf.Module.SubDir,
+		},
+		Filename:     jf.Filename,
+		Package:      jf.Package,
+		Imports:      make([]ParsedImport, 0, len(jf.Imports)),
+		Declarations: make([]ParsedDeclaration, len(jf.Declarations)),
+		Constants:    make([]ParsedConstant, len(jf.Constants)),
+		Methods:      make([]ParsedMethod, len(jf.Methods)),
+	}
+	for _, ji := range jf.Imports {
+		pf.Imports = append(pf.Imports, ParsedImport{
+			Name:         ji.Name,
+			ImplicitName: ji.ImplicitName,
+			Path:         ji.Path,
+		})
+	}
+
+	for idx, jd := range jf.Declarations {
+		pd := &pf.Declarations[idx]
+
+		pd.Name = jd.Name
+		pd.TypeParams, err = dec.decodeTypeParams(jd.TypeParams)
+		if err == nil {
+			pd.Type, err = dec.decodeType(jd.Type)
+		}
+		dec.popScope()
+		if err != nil {
+			return fmt.Errorf("unable to decode declaration %s [err=%v]", jd.Name, err)
+		}
+		pd.Tags = jd.Tags
+		if pd.Tags == nil {
+			pd.Tags = make(ParsedTags)
+		}
+		pd.IsAlias = jd.IsAlias
+		pd.Doc = jd.Doc
+		pd.file = pf
+	}
+
+	for idx, jc := range jf.Constants {
+		pc := &pf.Constants[idx]
+
+		pc.Name = jc.Name
+		pc.Type, err = dec.decodeType(jc.Type)
+		if err == nil {
+			pc.ParsedValue, err = decodeConstantValue(jc.ParsedValue)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to decode constant %s [err=%v]", jc.Name, err)
+		}
+		pc.Value = jc.Value
+		pc.Iota = jc.Iota
+		pc.valueExpr = dec.exprs.parse(jc.Value)
+	}
+
+	for idx, jm := range jf.Methods {
+		pm := &pf.Methods[idx]
+
+		pm.Name = jm.Name
+		pm.Receiver = jm.Receiver
+		pm.PointerReceiver = jm.PointerReceiver
+		pm.ReceiverTypeParams, err = dec.decodeTypeParams(jm.ReceiverTypeParams)
+		if err == nil && jm.Type != nil {
+			var t ParsedType
+
+			t, err = dec.decodeType(jm.Type)
+			if err == nil {
+				var ok bool
+
+				pm.Type, ok = t.(*ParsedFunction)
+				if !ok {
+					err = errors.New("not a function")
+				}
+			}
+		}
+		dec.popScope()
+		if err != nil {
+			return fmt.Errorf("unable to decode method %s.%s [err=%v]", jm.Receiver, jm.Name, err)
+		}
+	}
+	pf.fileContent = dec.exprs.content.String()
+
+	// Done
+	return nil
+}
+
+// decodeTypeParams decodes a type parameter list and opens a new scope with its identifiers. The
+// scope must be closed with popScope even if an error is returned.
+func (dec *jsonDecoder) decodeTypeParams(jfields []jsonField) ([]ParsedField, error) {
+	var err error
+
+	scope := make(map[string]*ParsedField)
+	dec.scopes = append(dec.scopes, scope)
+
+	// Allocate the parameters first because constraints may refer to any of them
+	typeParams := make([]ParsedField, len(jfields))
+	for idx, jfield := range jfields {
+		typeParams[idx].Names = append(make([]string, 0, len(jfield.Names)), jfield.Names...)
+		for _, name := range jfield.Names {
+			scope[name] = &typeParams[idx]
+		}
+	}
+	for idx, jfield := range jfields {
+		typeParams[idx].Type, err = dec.decodeType(jfield.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return typeParams, nil
+}
+
+func (dec *jsonDecoder) popScope() {
+	dec.scopes = dec.scopes[:len(dec.scopes)-1]
+}
+
+func (dec *jsonDecoder) decodeFields(jfields []jsonField) ([]ParsedField, error) {
+	var err error
+
+	fields := make([]ParsedField, len(jfields))
+	for idx, jfield := range jfields {
+		fields[idx] = ParsedField{
+			Names:        append(make([]string, 0, len(jfield.Names)), jfield.Names...),
+			ImplicitName: jfield.ImplicitName,
+			Tags:         jfield.Tags,
+			Doc:          jfield.Doc,
+			rawTag:       jfield.RawTag,
+		}
+		fields[idx].Type, err = dec.decodeType(jfield.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+func (dec *jsonDecoder) decodeType(jt *jsonType) (ParsedType, error) {
+	var err error
+
+	if jt == nil {
+		return nil, nil
+	}
+
+	switch jt.Kind {
+	case "native":
+		return &ParsedNativeType{
+			Name: jt.Name,
+		}, nil
+
+	case "non-native":
+		pnnt := &ParsedNonNativeType{
+			Name:       jt.Name,
+			ImportPath: jt.ImportPath,
+		}
+		if len(jt.Ref) > 0 {
+			dec.pending = append(dec.pending, pendingRef{
+				pnnt: pnnt,
+				id:   jt.Ref,
+			})
+		}
+		if jt.Instance != nil {
+			var t ParsedType
+
+			// Nested instances are added first, so they are re-created before this one
+			t, err = dec.decodeType(jt.Instance)
+			if err != nil {
+				return nil, err
+			}
+			pi, ok := t.(*ParsedIndex)
+			if !ok {
+				return nil, fmt.Errorf("instance %s is not an index expression", jt.Name)
+			}
+			dec.instances = append(dec.instances, pendingInstance{
+				pnnt: pnnt,
+				pi:   pi,
+			})
+		}
+		return pnnt, nil
+
+	case "type-param":
+		ptpr := &ParsedTypeParamRef{
+			Name: jt.Name,
+		}
+		for idx := len(dec.scopes) - 1; idx >= 0; idx-- {
+			if param, ok := dec.scopes[idx][jt.Name]; ok {
+				ptpr.Param = param
+				break
+			}
+		}
+		return ptpr, nil
+
+	case "struct":
+		ps := &ParsedStruct{}
+		ps.Fields, err = dec.decodeFields(jt.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return ps, nil
+
+	case "interface":
+		pi := &ParsedInterface{
+			Incomplete: jt.Incomplete,
+		}
+		pi.Methods, err = dec.decodeFields(jt.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return pi, nil
+
+	case "map":
+		pm := &ParsedMap{}
+		pm.KeyType, err = dec.decodeType(jt.Key)
+		if err == nil {
+			pm.ValueType, err = dec.decodeType(jt.Elem)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return pm, nil
+
+	case "array", "slice":
+		pa := &ParsedArray{
+			Size:      jt.Size,
+			ParsedInt: jt.Length,
+		}
+		pa.sizeExpr = dec.exprs.parse(jt.Size)
+		if len(jt.SizeError) > 0 {
+			pa.SizeError = errors.New(jt.SizeError)
+		}
+		pa.ValueType, err = dec.decodeType(jt.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return pa, nil
+
+	case "pointer":
+		pp := &ParsedPointer{}
+		pp.ToType, err = dec.decodeType(jt.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return pp, nil
+
+	case "channel":
+		pc := &ParsedChannel{}
+		switch jt.Dir {
+		case "send":
+			pc.Dir = ast.SEND
+		case "recv":
+			pc.Dir = ast.RECV
+		default:
+			pc.Dir = ast.SEND | ast.RECV
+		}
+		pc.Type, err = dec.decodeType(jt.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return pc, nil
+
+	case "function":
+		pfunc := &ParsedFunction{
+			Variadic: jt.Variadic,
+		}
+		pfunc.TypeParams, err = dec.decodeTypeParams(jt.TypeParams)
+		if err == nil {
+			pfunc.Params, err = dec.decodeFields(jt.Params)
+		}
+		if err == nil {
+			pfunc.Results, err = dec.decodeFields(jt.Results)
+		}
+		dec.popScope()
+		if err != nil {
+			return nil, err
+		}
+		return pfunc, nil
+
+	case "index":
+		pi := &ParsedIndex{
+			Indexes: make([]ParsedType, 0, len(jt.Indexes)),
+		}
+		pi.Type, err = dec.decodeType(jt.Elem)
+		if err != nil {
+			return nil, err
+		}
+		for _, jidx := range jt.Indexes {
+			var idx ParsedType
+
+			idx, err = dec.decodeType(jidx)
+			if err != nil {
+				return nil, err
+			}
+			pi.Indexes = append(pi.Indexes, idx)
+		}
+		return pi, nil
+
+	case "union":
+		pu := &ParsedUnion{
+			Terms: make([]ParsedUnionTerm, 0, len(jt.Terms)),
+		}
+		for _, jterm := range jt.Terms {
+			term := ParsedUnionTerm{
+				Tilde: jterm.Tilde,
+			}
+			term.Type, err = dec.decodeType(jterm.Type)
+			if err != nil {
+				return nil, err
+			}
+			pu.Terms = append(pu.Terms, term)
+		}
+		return pu, nil
+	}
+
+	return nil, fmt.Errorf("unknown kind %s", jt.Kind)
+}
+
+// relink sets the references of the decoded nodes, re-creates the instances of generic types and
+// attaches methods to their receiver types
+func (dec *jsonDecoder) relink(ps *PackageSet) error {
+	for _, pr := range dec.pending {
+		dotIdx := strings.LastIndex(pr.id, ".")
+		if dotIdx < 0 {
+			continue
+		}
+		pr.pnnt.Ref = ps.Lookup(pr.id[:dotIdx], pr.id[dotIdx+1:])
+	}
+
+	for _, inst := range dec.instances {
+		if generic, ok := inst.pi.Type.(*ParsedNonNativeType); ok && generic.Ref == nil {
+			continue // The generic type is declared in a file that is not part of the set
+		}
+		pd, err := Instantiate(inst.pi)
+		if err != nil {
+			return fmt.Errorf("unable to instantiate %s [err=%v]", inst.pnnt.Name, err)
+		}
+		inst.pnnt.Ref = pd
+	}
+
+	for _, pf := range ps.files {
+		for pmIdx := range pf.Methods {
+			pm := &pf.Methods[pmIdx]
+			if pd := ps.Lookup(pf.Module.FullName(), pm.Receiver); pd != nil {
+				attachMethod(pd, pm)
+			}
+		}
+	}
+
+	// Done
+	return nil
+}
+
+// parse returns the expression of the given source text or nil if it is empty or invalid
+func (ep *exprParser) parse(text string) ast.Expr {
+	if len(text) == 0 {
+		return nil
+	}
+
+	// Each expression is added to the file set as a new file, leaving a one position gap between
+	// them, so the text is stored at the offset its positions point to
+	expr, err := goparser.ParseExprFrom(ep.fset, "", text, 0)
+	ep.content.WriteString(text + " ")
+	if err != nil {
+		return nil
+	}
+	return expr
+}
+
+func decodeConstantValue(jv *jsonConstantValue) (constant.Value, error) {
+	if jv == nil {
+		return nil, nil
+	}
+
+	switch jv.Kind {
+	case "bool":
+		return constant.MakeBool(jv.Value == "true"), nil
+	case "string":
+		return constant.MakeString(jv.Value), nil
+	case "int", "float":
+		return parseExactValue(jv.Value)
+	case "complex":
+		re, err := parseExactValue(jv.Value)
+		if err != nil {
+			return nil, err
+		}
+		im, err := parseExactValue(jv.Imag)
+		if err != nil {
+			return nil, err
+		}
+		return constant.BinaryOp(re, token.ADD, constant.MakeImag(im)), nil
+	}
+	return nil, fmt.Errorf("unknown constant kind %s", jv.Kind)
+}
+
+// parseExactValue parses a numeric value returned by constant.Value.ExactString, which may be a
+// fraction like 1/3
+func parseExactValue(s string) (constant.Value, error) {
+	parse := func(lit string) constant.Value {
+		if strings.ContainsAny(lit, ".eEpP") && !strings.HasPrefix(lit, "0x") {
+			return constant.MakeFromLiteral(lit, token.FLOAT, 0)
+		}
+		return constant.MakeFromLiteral(lit, token.INT, 0)
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	var v constant.Value
+	if slashIdx := strings.Index(s, "/"); slashIdx >= 0 {
+		num := parse(s[:slashIdx])
+		den := parse(s[slashIdx+1:])
+		if num.Kind() == constant.Unknown || den.Kind() == constant.Unknown {
+			return nil, fmt.Errorf("invalid constant value %s", s)
+		}
+		v = constant.BinaryOp(num, token.QUO, den)
+	} else {
+		v = parse(s)
+	}
+	if v.Kind() == constant.Unknown {
+		return nil, fmt.Errorf("invalid constant value %s", s)
+	}
+	if neg {
+		v = constant.UnaryOp(token.SUB, v, 0)
+	}
+	return v, nil
+}
@@ -0,0 +1,439 @@
+package jsonschema
+
+import (
+	"fmt"
+	"go/constant"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+type Options struct {
+	// ID is stored in the $id keyword of the root schema
+	ID string
+
+	// DefinitionsPath is the prefix of the references to definitions, defaults to "#/$defs/".
+	// Generators embedding the definitions elsewhere, like OpenAPI documents, can change it.
+	DefinitionsPath string
+
+	// RefNamedTypes stores every named type in the definitions instead of inlining it. If not set,
+	// only recursive types are stored as definitions.
+	RefNamedTypes bool
+
+	// DisallowAdditionalProperties sets additionalProperties to false in the schemas of structs
+	DisallowAdditionalProperties bool
+
+	// Packages, if set, is used to find the constants declared with a named type, which are
+	// emitted as the enum values of the type.
+	Packages *parser.PackageSet
+
+	// FieldHook, if set, is called with the schema of each struct field so it can be customized
+	FieldHook func(field *parser.ParsedField, s *Schema) error
+}
+
+// Generator converts parsed types to JSON Schemas. Definitions needed by the generated schemas,
+// like recursive types, are accumulated in the generator and can be retrieved with Definitions.
+type Generator struct {
+	opts      Options
+	defs      map[string]*Schema
+	defNames  map[string]string
+	usedNames map[string]struct{}
+	visiting  map[string]struct{}
+	recursive map[string]struct{}
+}
+
+// -----------------------------------------------------------------------------
+
+var wellKnownTypes = map[string]func() *Schema{
+	"time.Time": func() *Schema {
+		return &Schema{
+			Type:   Types{"string"},
+			Format: "date-time",
+		}
+	},
+	"time.Duration": func() *Schema {
+		return &Schema{
+			Type: Types{"integer"},
+		}
+	},
+	"encoding/json.RawMessage": func() *Schema {
+		return &Schema{}
+	},
+	"net/url.URL": func() *Schema {
+		return &Schema{
+			Type:   Types{"string"},
+			Format: "uri",
+		}
+	},
+	"github.com/google/uuid.UUID": func() *Schema {
+		return &Schema{
+			Type:   Types{"string"},
+			Format: "uuid",
+		}
+	},
+}
+
+// Generate returns the JSON Schema document of a declaration. References must be resolved before
+// calling this function.
+func Generate(pd *parser.ParsedDeclaration, opts Options) (*Schema, error) {
+	g := NewGenerator(opts)
+
+	s, err := g.Declaration(pd)
+	if err != nil {
+		return nil, err
+	}
+
+	root := s
+	if len(s.Ref) > 0 {
+		// The root type is stored in the definitions, keep the reference only
+		root = &Schema{
+			Ref: s.Ref,
+		}
+	}
+	root.Schema = Draft
+	root.ID = opts.ID
+	if len(root.Title) == 0 {
+		root.Title = pd.Name
+	}
+	if defs := g.Definitions(); len(defs) > 0 {
+		root.Defs = defs
+	}
+
+	// Done
+	return root, nil
+}
+
+// NewGenerator creates a new schema generator
+func NewGenerator(opts Options) *Generator {
+	if len(opts.DefinitionsPath) == 0 {
+		opts.DefinitionsPath = "#/$defs/"
+	}
+	return &Generator{
+		opts:      opts,
+		defs:      make(map[string]*Schema),
+		defNames:  make(map[string]string),
+		usedNames: make(map[string]struct{}),
+		visiting:  make(map[string]struct{}),
+		recursive: make(map[string]struct{}),
+	}
+}
+
+// Definitions returns the definitions referenced by the schemas generated so far, keyed by name
+func (g *Generator) Definitions() map[string]*Schema {
+	return g.defs
+}
+
+// Declaration returns the schema of a declaration
+func (g *Generator) Declaration(pd *parser.ParsedDeclaration) (*Schema, error) {
+	if len(pd.TypeParams) > 0 {
+		return nil, fmt.Errorf("generic declaration %s must be instantiated", pd.Name)
+	}
+	return g.Schema(&parser.ParsedNonNativeType{
+		Name: pd.Name,
+		Ref:  pd,
+	})
+}
+
+// Schema returns the schema of a type following the rules of the encoding/json package
+func (g *Generator) Schema(t parser.ParsedType) (*Schema, error) {
+	switch tType := t.(type) {
+	case *parser.ParsedNativeType:
+		return nativeSchema(tType.Name)
+
+	case *parser.ParsedNonNativeType:
+		id := codegen.ID(tType)
+		if fn, ok := wellKnownTypes[id]; ok {
+			return fn(), nil
+		}
+		if tType.Ref == nil {
+			if id == "any" || id == "error" {
+				return &Schema{}, nil
+			}
+			return nil, fmt.Errorf("unresolved reference to %s", tType.Name)
+		}
+
+		pd := tType.Ref
+		if pd.IsAlias {
+			return g.Schema(pd.Type)
+		}
+		if len(pd.TypeParams) > 0 {
+			return nil, fmt.Errorf("generic type %s must be instantiated", pd.Name)
+		}
+		return g.named(tType, codegen.DeclarationKey(pd), pd.Name, pd)
+
+	case *parser.ParsedIndex:
+		generic, ok := tType.Type.(*parser.ParsedNonNativeType)
+		if !ok || generic.Ref == nil {
+			return nil, fmt.Errorf("unresolved generic type %s", parser.Render(tType, nil))
+		}
+		pd, err := parser.Instantiate(tType)
+		if err != nil {
+			return nil, fmt.Errorf("unable to instantiate %s [err=%v]", parser.Render(tType, nil), err)
+		}
+		pd.Doc = generic.Ref.Doc
+		return g.named(tType, codegen.DeclarationKey(pd), pd.Name, pd)
+
+	case *parser.ParsedTypeParamRef:
+		return nil, fmt.Errorf("type parameter %s must be instantiated", tType.Name)
+
+	case *parser.ParsedStruct:
+		return g.structSchema(tType)
+
+	case *parser.ParsedInterface:
+		return &Schema{}, nil
+
+	case *parser.ParsedMap:
+		value, err := g.Schema(tType.ValueType)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{
+			Type:                 Types{"object"},
+			AdditionalProperties: value,
+		}, nil
+
+	case *parser.ParsedArray:
+		if len(tType.Size) == 0 && codegen.IsByte(tType.ValueType) {
+			// Byte slices are encoded as base64 strings
+			return &Schema{
+				Type:            Types{"string"},
+				ContentEncoding: "base64",
+			}, nil
+		}
+
+		items, err := g.Schema(tType.ValueType)
+		if err != nil {
+			return nil, err
+		}
+		s := &Schema{
+			Type:  Types{"array"},
+			Items: items,
+		}
+		if len(tType.Size) > 0 {
+			if tType.ParsedInt == nil {
+				return nil, fmt.Errorf("unable to determine the length of array [%s]", tType.Size)
+			}
+			s.MinItems = tType.ParsedInt
+			s.MaxItems = tType.ParsedInt
+		}
+		return s, nil
+
+	case *parser.ParsedPointer:
+		s, err := g.Schema(tType.ToType)
+		if err != nil {
+			return nil, err
+		}
+		return Nullable(s), nil
+	}
+
+	return nil, fmt.Errorf("type %s cannot be represented in JSON", parser.Render(t, nil))
+}
+
+// Nullable returns a schema that also accepts null values
+func Nullable(s *Schema) *Schema {
+	if len(s.Ref) == 0 && len(s.AnyOf) == 0 && len(s.Type) > 0 {
+		for _, t := range s.Type {
+			if t == "null" {
+				return s
+			}
+		}
+		newS := *s
+		newS.Type = append(append(make(Types, 0, len(s.Type)+1), s.Type...), "null")
+		return &newS
+	}
+	if isEmptySchema(s) {
+		return s // Already accepts anything
+	}
+	return &Schema{
+		AnyOf: []*Schema{
+			s,
+			{
+				Type: Types{"null"},
+			},
+		},
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// named generates the schema of a declared type. If the type is recursive, or named structs must
+// be referenced, the schema is stored in the definitions and a reference is returned.
+func (g *Generator) named(t parser.ParsedType, key string, name string, pd *parser.ParsedDeclaration) (*Schema, error) {
+	if _, ok := g.visiting[key]; ok {
+		g.recursive[key] = struct{}{}
+		return g.ref(key, name), nil
+	}
+	if defName, ok := g.defNames[key]; ok {
+		if _, ok = g.defs[defName]; ok {
+			return g.ref(key, name), nil
+		}
+	}
+
+	// Types with custom marshallers are not described by their fields
+	switch codegen.Marshaler(t) {
+	case "MarshalJSON":
+		return &Schema{
+			Description: pd.Doc,
+		}, nil
+	case "MarshalText":
+		return &Schema{
+			Type:        Types{"string"},
+			Description: pd.Doc,
+		}, nil
+	}
+
+	g.visiting[key] = struct{}{}
+	s, err := g.Schema(pd.Type)
+	delete(g.visiting, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate schema of %s [err=%v]", name, err)
+	}
+	if len(s.Description) == 0 && len(s.Ref) == 0 {
+		s.Description = pd.Doc
+	}
+	if g.opts.Packages != nil {
+		if enum := enumValues(g.opts.Packages.EnumValues(pd)); len(enum) > 0 {
+			s.Enum = enum
+		}
+	}
+
+	_, isRecursive := g.recursive[key]
+	if isRecursive || g.opts.RefNamedTypes {
+		ref := g.ref(key, name)
+		g.defs[g.defNames[key]] = s
+		return ref, nil
+	}
+	return s, nil
+}
+
+func (g *Generator) ref(key string, name string) *Schema {
+	defName, ok := g.defNames[key]
+	if !ok {
+		defName = codegen.TypeName(name)
+		if _, used := g.usedNames[defName]; used {
+			for idx := 2; ; idx++ {
+				candidate := fmt.Sprintf("%s%d", defName, idx)
+				if _, used = g.usedNames[candidate]; !used {
+					defName = candidate
+					break
+				}
+			}
+		}
+		g.usedNames[defName] = struct{}{}
+		g.defNames[key] = defName
+	}
+	return &Schema{
+		Ref: g.opts.DefinitionsPath + defName,
+	}
+}
+
+func (g *Generator) structSchema(ps *parser.ParsedStruct) (*Schema, error) {
+	fields := ps.JSONFields()
+
+	s := &Schema{
+		Type:       Types{"object"},
+		Properties: make(Properties, 0, len(fields)),
+	}
+	for _, jf := range fields {
+		var fieldSchema *Schema
+		var err error
+
+		if jf.AsString && codegen.IsStringable(jf.Field.Type) {
+			fieldSchema = &Schema{
+				Type: Types{"string"},
+			}
+		} else {
+			fieldSchema, err = g.Schema(jf.Field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("unable to generate schema of field %s [err=%v]", jf.Name, err)
+			}
+		}
+		if len(jf.Field.Doc) > 0 {
+			fieldSchema.Description = jf.Field.Doc
+		}
+		if g.opts.FieldHook != nil {
+			err = g.opts.FieldHook(jf.Field, fieldSchema)
+			if err != nil {
+				return nil, fmt.Errorf("unable to customize schema of field %s [err=%v]", jf.Name, err)
+			}
+		}
+
+		s.Properties = append(s.Properties, Property{
+			Name:   jf.Name,
+			Schema: fieldSchema,
+		})
+		if !jf.OmitEmpty {
+			s.Required = append(s.Required, jf.Name)
+		}
+	}
+	if g.opts.DisallowAdditionalProperties {
+		s.AdditionalProperties = false
+	}
+
+	// Done
+	return s, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// enumValues converts evaluated constants to JSON values. Constants that were not evaluated are
+// skipped.
+func enumValues(constants []*parser.ParsedConstant) []interface{} {
+	values := make([]interface{}, 0, len(constants))
+	for _, pc := range constants {
+		if pc.ParsedValue == nil {
+			continue
+		}
+		switch pc.ParsedValue.Kind() {
+		case constant.Bool:
+			values = append(values, constant.BoolVal(pc.ParsedValue))
+		case constant.String:
+			values = append(values, constant.StringVal(pc.ParsedValue))
+		case constant.Int:
+			if v, exact := constant.Int64Val(pc.ParsedValue); exact {
+				values = append(values, v)
+			} else if v, exact := constant.Uint64Val(pc.ParsedValue); exact {
+				values = append(values, v)
+			}
+		case constant.Float:
+			v, _ := constant.Float64Val(pc.ParsedValue)
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func nativeSchema(name string) (*Schema, error) {
+	switch name {
+	case "bool":
+		return &Schema{
+			Type: Types{"boolean"},
+		}, nil
+	case "string":
+		return &Schema{
+			Type: Types{"string"},
+		}, nil
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return &Schema{
+			Type: Types{"integer"},
+		}, nil
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		zero := float64(0)
+		return &Schema{
+			Type:    Types{"integer"},
+			Minimum: &zero,
+		}, nil
+	case "float32", "float64":
+		return &Schema{
+			Type: Types{"number"},
+		}, nil
+	}
+	return nil, fmt.Errorf("type %s cannot be represented in JSON", name)
+}
+
+func isEmptySchema(s *Schema) bool {
+	return len(s.Ref) == 0 && len(s.Type) == 0 && len(s.AnyOf) == 0 && len(s.Properties) == 0 &&
+		s.Items == nil && s.AdditionalProperties == nil && len(s.Enum) == 0
+}
@@ -0,0 +1,219 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/jsonschema"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"time"
+)
+
+const Size = 2
+
+// Base contains common fields
+type Base struct {
+	ID      string    ` + "`json:\"id\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+}
+
+// User is a registered user
+type User struct {
+	Base
+	Name     string            ` + "`json:\"name\"`" + ` // Full name
+	Email    *string           ` + "`json:\"email,omitempty\"`" + `
+	Password string            ` + "`json:\"-\"`" + `
+	Labels   map[string]string ` + "`json:\"labels,omitempty\"`" + `
+	Point    [Size]float64     ` + "`json:\"point\"`" + `
+	Avatar   []byte            ` + "`json:\"avatar,omitempty\"`" + `
+	Friends  []*User           ` + "`json:\"friends,omitempty\"`" + `
+	Age      uint8             ` + "`json:\"age,string\"`" + `
+	secret   string
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	s, err := jsonschema.Generate(&pf.Declarations[1], jsonschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	data, _ := json.Marshal(s)
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","$ref":"#/$defs/User","title":"User",` +
+		`"$defs":{"User":{"description":"User is a registered user","type":"object","properties":{` +
+		`"id":{"type":"string"},` +
+		`"created":{"type":"string","format":"date-time"},` +
+		`"name":{"description":"Full name","type":"string"},` +
+		`"email":{"type":["string","null"]},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"point":{"type":"array","items":{"type":"number"},"minItems":2,"maxItems":2},` +
+		`"avatar":{"type":"string","contentEncoding":"base64"},` +
+		`"friends":{"type":"array","items":{"anyOf":[{"$ref":"#/$defs/User"},{"type":"null"}]}},` +
+		`"age":{"type":"string"}},` +
+		`"required":["id","created","name","point","age"]}}}`
+	if string(data) != expected {
+		t.Fatalf("wrong schema: %v", string(data))
+	}
+}
+
+func TestUnsupportedTypes(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Tree map[string]Tree
+
+type Job struct {
+	Done chan bool ` + "`json:\"-\"`" + `
+	Run  func()    ` + "`json:\"-\"`" + `
+	Name string    ` + "`json:\"name\"`" + `
+	Tree Tree      ` + "`json:\"tree\"`" + `
+}
+
+type Point struct {
+	Value complex128 ` + "`json:\"value\"`" + `
+}
+
+type Vector struct {
+	Coords [Dimensions]float64 ` + "`json:\"coords\"`" + `
+}
+
+type Task struct {
+	Run func() ` + "`json:\"run\"`" + `
+}
+
+type worker struct {
+	Jobs chan int
+}
+
+type Pool struct {
+	worker
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	// Fields excluded with the json tag are not converted and recursive maps are stored in $defs
+	s, err := jsonschema.Generate(&pf.Declarations[1], jsonschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+	data, _ := json.Marshal(s)
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"Job","type":"object",` +
+		`"properties":{"name":{"type":"string"},"tree":{"$ref":"#/$defs/Tree"}},"required":["name","tree"],` +
+		`"$defs":{"Tree":{"type":"object","additionalProperties":{"$ref":"#/$defs/Tree"}}}}`
+	if string(data) != expected {
+		t.Fatalf("wrong schema:\n%v", string(data))
+	}
+
+	// Values that encoding/json cannot encode, including promoted fields, are reported
+	for _, tc := range []struct {
+		pd       *parser.ParsedDeclaration
+		expected string
+	}{
+		{&pf.Declarations[2], "unable to generate schema of field value [err=type complex128 cannot be represented in JSON]"},
+		{&pf.Declarations[3], "unable to generate schema of field coords [err=unable to determine the length of array [Dimensions]]"},
+		{&pf.Declarations[4], "unable to generate schema of field run [err=type func() cannot be represented in JSON]"},
+		{&pf.Declarations[6], "unable to generate schema of field Jobs [err=type chan int cannot be represented in JSON]"},
+	} {
+		_, err = jsonschema.Generate(tc.pd, jsonschema.Options{})
+		if err == nil || err.Error() != "unable to generate schema of "+tc.pd.Name+" [err="+tc.expected+"]" {
+			t.Fatalf("wrong error for %v: %v", tc.pd.Name, err)
+		}
+	}
+}
+
+func TestNameCollision(t *testing.T) {
+	common, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package common
+
+type User struct {
+	Email string ` + "`json:\"email\"`" + `
+}
+`,
+		Filename: "common.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "common",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+type Page[T any] struct {
+	Items []T ` + "`json:\"items\"`" + `
+}
+
+type User struct {
+	Account common.User       ` + "`json:\"account\"`" + `
+	Friends Page[common.User] ` + "`json:\"friends\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{common, pf})
+	ps.ResolveReferences()
+
+	s, err := jsonschema.Generate(ps.Lookup("github.com/mxmauro/gofile-parser-test", "User"), jsonschema.Options{
+		RefNamedTypes: true,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate schema [err=%v]", err)
+	}
+
+	data, _ := json.Marshal(s)
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","$ref":"#/$defs/User2","title":"User",` +
+		`"$defs":{"Page_common_User":{"type":"object","properties":{` +
+		`"items":{"type":"array","items":{"$ref":"#/$defs/User"}}},"required":["items"]},` +
+		`"User":{"type":"object","properties":{"email":{"type":"string"}},"required":["email"]},` +
+		`"User2":{"type":"object","properties":{` +
+		`"account":{"$ref":"#/$defs/User"},` +
+		`"friends":{"$ref":"#/$defs/Page_common_User"}},` +
+		`"required":["account","friends"]}}}`
+	if string(data) != expected {
+		t.Fatalf("wrong schema: %v", string(data))
+	}
+}
@@ -0,0 +1,101 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// -----------------------------------------------------------------------------
+
+// Draft is the identifier of the JSON Schema version generated by this package
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema. Only the keywords needed to describe Go types
+// are available.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int64             `json:"minLength,omitempty"`
+	MaxLength            *int64             `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           Properties         `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // A *Schema or a bool
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int64             `json:"minItems,omitempty"`
+	MaxItems             *int64             `json:"maxItems,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	WriteOnly            bool               `json:"writeOnly,omitempty"`
+	Examples             []interface{}      `json:"examples,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Types is the list of allowed JSON types. It is encoded as a string if it contains only one type.
+type Types []string
+
+// Properties is the ordered list of properties of an object
+type Properties []Property
+
+// Property is a named property of an object
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// -----------------------------------------------------------------------------
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// MarshalJSON encodes the properties as a JSON object keeping their order
+func (props Properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for idx, prop := range props {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(prop.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	// Done
+	return buf.Bytes(), nil
+}
+
+// Get returns the schema of the property with the given name or nil if not found
+func (props Properties) Get(name string) *Schema {
+	for _, prop := range props {
+		if prop.Name == name {
+			return prop.Schema
+		}
+	}
+	return nil
+}
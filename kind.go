@@ -0,0 +1,140 @@
+package parser
+
+// -----------------------------------------------------------------------------
+
+// ParsedType is implemented by all the nodes that describe a type. The interface is sealed, only
+// the nodes defined in this package implement it.
+type ParsedType interface {
+	Kind() Kind
+	String() string
+
+	isParsedType()
+}
+
+// Kind identifies the specific node a ParsedType contains
+type Kind int
+
+const (
+	InvalidKind Kind = iota
+	NativeKind
+	NonNativeKind
+	TypeParamKind
+	StructKind
+	InterfaceKind
+	MapKind
+	ArrayKind
+	SliceKind
+	PointerKind
+	ChannelKind
+	FunctionKind
+	IndexKind
+	UnionKind
+)
+
+// -----------------------------------------------------------------------------
+
+// AsParsedType converts a value stored in a generic interface{} to a ParsedType. It is intended
+// for callers that kept nodes in interface{} variables before the model used ParsedType. Nil is
+// returned if the value is not a node of this package.
+func AsParsedType(v interface{}) ParsedType {
+	if t, ok := v.(ParsedType); ok {
+		return t
+	}
+	return nil
+}
+
+func (k Kind) String() string {
+	switch k {
+	case NativeKind:
+		return "native"
+	case NonNativeKind:
+		return "non-native"
+	case TypeParamKind:
+		return "type-param"
+	case StructKind:
+		return "struct"
+	case InterfaceKind:
+		return "interface"
+	case MapKind:
+		return "map"
+	case ArrayKind:
+		return "array"
+	case SliceKind:
+		return "slice"
+	case PointerKind:
+		return "pointer"
+	case ChannelKind:
+		return "channel"
+	case FunctionKind:
+		return "function"
+	case IndexKind:
+		return "index"
+	case UnionKind:
+		return "union"
+	}
+	return "invalid"
+}
+
+func (*ParsedNativeType) Kind() Kind {
+	return NativeKind
+}
+
+func (*ParsedNonNativeType) Kind() Kind {
+	return NonNativeKind
+}
+
+func (*ParsedTypeParamRef) Kind() Kind {
+	return TypeParamKind
+}
+
+func (*ParsedStruct) Kind() Kind {
+	return StructKind
+}
+
+func (*ParsedInterface) Kind() Kind {
+	return InterfaceKind
+}
+
+func (*ParsedMap) Kind() Kind {
+	return MapKind
+}
+
+func (pa *ParsedArray) Kind() Kind {
+	if len(pa.Size) == 0 {
+		return SliceKind
+	}
+	return ArrayKind
+}
+
+func (*ParsedPointer) Kind() Kind {
+	return PointerKind
+}
+
+func (*ParsedChannel) Kind() Kind {
+	return ChannelKind
+}
+
+func (*ParsedFunction) Kind() Kind {
+	return FunctionKind
+}
+
+func (*ParsedIndex) Kind() Kind {
+	return IndexKind
+}
+
+func (*ParsedUnion) Kind() Kind {
+	return UnionKind
+}
+
+func (*ParsedNativeType) isParsedType()    {}
+func (*ParsedNonNativeType) isParsedType() {}
+func (*ParsedTypeParamRef) isParsedType()  {}
+func (*ParsedStruct) isParsedType()        {}
+func (*ParsedInterface) isParsedType()     {}
+func (*ParsedMap) isParsedType()           {}
+func (*ParsedArray) isParsedType()         {}
+func (*ParsedPointer) isParsedType()       {}
+func (*ParsedChannel) isParsedType()       {}
+func (*ParsedFunction) isParsedType()      {}
+func (*ParsedIndex) isParsedType()         {}
+func (*ParsedUnion) isParsedType()         {}
@@ -0,0 +1,318 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// Sizes contains the word size and maximum alignment of a target architecture. It is used to
+// compute the memory layout of types the same way the gc compiler does.
+type Sizes struct {
+	WordSize int64 // Size of a word in bytes
+	MaxAlign int64 // Maximum alignment in bytes
+}
+
+// Layout describes the memory layout of a type
+type Layout struct {
+	Size   int64
+	Align  int64
+	Fields []FieldLayout // Only set for structs
+}
+
+// FieldLayout describes the position of a struct field. Fields that share a definition, like
+// `A, B int`, have one entry per name.
+type FieldLayout struct {
+	Name   string
+	Field  *ParsedField
+	Offset int64
+	Size   int64
+	Align  int64
+}
+
+type sizer struct {
+	sizes    *Sizes
+	visiting map[string]struct{}
+}
+
+// -----------------------------------------------------------------------------
+
+var gcArchSizes = map[string]*Sizes{
+	"386":      {4, 4},
+	"amd64":    {8, 8},
+	"amd64p32": {4, 8},
+	"arm":      {4, 4},
+	"armbe":    {4, 4},
+	"arm64":    {8, 8},
+	"arm64be":  {8, 8},
+	"loong64":  {8, 8},
+	"mips":     {4, 4},
+	"mipsle":   {4, 4},
+	"mips64":   {8, 8},
+	"mips64le": {8, 8},
+	"ppc64":    {8, 8},
+	"ppc64le":  {8, 8},
+	"riscv64":  {8, 8},
+	"s390x":    {8, 8},
+	"sparc64":  {8, 8},
+	"wasm":     {8, 8},
+}
+
+var gccgoArchSizes = map[string]*Sizes{
+	"386":      {4, 4},
+	"alpha":    {8, 8},
+	"amd64":    {8, 8},
+	"amd64p32": {4, 8},
+	"arm":      {4, 8},
+	"armbe":    {4, 8},
+	"arm64":    {8, 8},
+	"arm64be":  {8, 8},
+	"ia64":     {8, 8},
+	"loong64":  {8, 8},
+	"m68k":     {4, 2},
+	"mips":     {4, 8},
+	"mipsle":   {4, 8},
+	"mips64":   {8, 8},
+	"mips64le": {8, 8},
+	"nios2":    {4, 4},
+	"ppc":      {4, 8},
+	"ppc64":    {8, 8},
+	"ppc64le":  {8, 8},
+	"riscv":    {4, 4},
+	"riscv64":  {8, 8},
+	"s390":     {4, 8},
+	"s390x":    {8, 8},
+	"sh":       {4, 4},
+	"shbe":     {4, 4},
+	"sparc":    {4, 8},
+	"sparc64":  {8, 8},
+	"wasm":     {8, 8},
+}
+
+// SizesFor returns the Sizes used by a compiler for an architecture, for example, SizesFor("gc",
+// "amd64"). Nil is returned if the combination is unknown.
+func SizesFor(compiler, arch string) *Sizes {
+	var m map[string]*Sizes
+
+	switch compiler {
+	case "gc":
+		m = gcArchSizes
+	case "gccgo":
+		m = gccgoArchSizes
+	default:
+		return nil
+	}
+	s, ok := m[arch]
+	if !ok {
+		return nil
+	}
+	return &Sizes{
+		WordSize: s.WordSize,
+		MaxAlign: s.MaxAlign,
+	}
+}
+
+// Layout computes the size and alignment of a type and, for structs, the offset of each field.
+// References must be resolved and array lengths evaluated before calling this method. An error is
+// returned if the size cannot be determined, for example, for unresolved references, type
+// parameters or arrays with unknown lengths.
+func (s *Sizes) Layout(t ParsedType) (*Layout, error) {
+	sz := sizer{
+		sizes:    s,
+		visiting: make(map[string]struct{}),
+	}
+	return sz.layout(t)
+}
+
+// Sizeof returns the size of a type in bytes
+func (s *Sizes) Sizeof(t ParsedType) (int64, error) {
+	l, err := s.Layout(t)
+	if err != nil {
+		return 0, err
+	}
+	return l.Size, nil
+}
+
+// Alignof returns the alignment of a type in bytes
+func (s *Sizes) Alignof(t ParsedType) (int64, error) {
+	l, err := s.Layout(t)
+	if err != nil {
+		return 0, err
+	}
+	return l.Align, nil
+}
+
+// Layout computes the memory layout of the declared type for the given architecture sizes
+func (pd *ParsedDeclaration) Layout(sizes *Sizes) (*Layout, error) {
+	if len(pd.TypeParams) > 0 {
+		return nil, fmt.Errorf("cannot compute the layout of generic type %s", pd.Name)
+	}
+	return sizes.Layout(pd.Type)
+}
+
+func (sz *sizer) layout(t ParsedType) (*Layout, error) {
+	word := sz.sizes.WordSize
+
+	switch tType := t.(type) {
+	case *ParsedNativeType:
+		return sz.nativeLayout(tType.Name)
+
+	case *ParsedNonNativeType:
+		if tType.Ref == nil {
+			// Predeclared and well-known types that are not parsed as native types
+			switch {
+			case tType.Name == "any" || tType.Name == "error":
+				return sz.basic(2*word, word), nil
+			case tType.ImportPath == "unsafe" || tType.Name == "unsafe.Pointer":
+				return sz.basic(word, word), nil
+			}
+			return nil, fmt.Errorf("cannot determine the size of unresolved type %s", tType.Name)
+		}
+		return sz.namedLayout(tType, tType.Ref.Type)
+
+	case *ParsedIndex:
+		pd, err := Instantiate(tType)
+		if err != nil {
+			return nil, fmt.Errorf("unable to instantiate %s [err=%v]", Render(tType, nil), err)
+		}
+		return sz.namedLayout(tType, pd.Type)
+
+	case *ParsedTypeParamRef:
+		return nil, fmt.Errorf("cannot determine the size of type parameter %s", tType.Name)
+
+	case *ParsedStruct:
+		return sz.structLayout(tType)
+
+	case *ParsedInterface:
+		return sz.basic(2*word, word), nil
+
+	case *ParsedMap, *ParsedPointer, *ParsedChannel, *ParsedFunction:
+		return sz.basic(word, word), nil
+
+	case *ParsedArray:
+		if len(tType.Size) == 0 {
+			return sz.basic(3*word, word), nil
+		}
+		if tType.ParsedInt == nil {
+			if tType.SizeError != nil {
+				return nil, fmt.Errorf("cannot determine the length of array [%s] [err=%v]", tType.Size, tType.SizeError)
+			}
+			return nil, fmt.Errorf("cannot determine the length of array [%s]", tType.Size)
+		}
+		elem, err := sz.layout(tType.ValueType)
+		if err != nil {
+			return nil, err
+		}
+		n := *tType.ParsedInt
+		size := int64(0)
+		if n > 0 {
+			size = alignTo(elem.Size, elem.Align)*(n-1) + elem.Size
+		}
+		return &Layout{
+			Size:  size,
+			Align: elem.Align,
+		}, nil
+
+	case *ParsedUnion:
+		return nil, fmt.Errorf("cannot determine the size of union %s", Render(tType, nil))
+	}
+
+	return nil, fmt.Errorf("cannot determine the size of %v", t)
+}
+
+func (sz *sizer) namedLayout(t ParsedType, underlying ParsedType) (*Layout, error) {
+	key := methodSetKey(t)
+	if _, ok := sz.visiting[key]; ok {
+		return nil, fmt.Errorf("invalid recursive type %s", Render(t, nil))
+	}
+	sz.visiting[key] = struct{}{}
+	defer delete(sz.visiting, key)
+
+	return sz.layout(underlying)
+}
+
+func (sz *sizer) structLayout(ps *ParsedStruct) (*Layout, error) {
+	l := &Layout{
+		Align:  1,
+		Fields: make([]FieldLayout, 0, len(ps.Fields)),
+	}
+
+	offset := int64(0)
+	for fieldIdx := range ps.Fields {
+		field := &ps.Fields[fieldIdx]
+
+		fl, err := sz.layout(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute the layout of field %s [err=%v]", strings.Join(field.Identifiers(), ","), err)
+		}
+		for _, name := range field.Identifiers() {
+			offset = alignTo(offset, fl.Align)
+			l.Fields = append(l.Fields, FieldLayout{
+				Name:   name,
+				Field:  field,
+				Offset: offset,
+				Size:   fl.Size,
+				Align:  fl.Align,
+			})
+			offset += fl.Size
+		}
+		if fl.Align > l.Align {
+			l.Align = fl.Align
+		}
+	}
+
+	// Like gc, add padding if the last field has zero size so a pointer to it does not point
+	// past the end of the struct
+	if n := len(l.Fields); n > 0 && l.Fields[n-1].Size == 0 && offset > 0 {
+		offset += 1
+	}
+	l.Size = alignTo(offset, l.Align)
+
+	// Done
+	return l, nil
+}
+
+func (sz *sizer) nativeLayout(name string) (*Layout, error) {
+	word := sz.sizes.WordSize
+
+	switch canonicalNativeName(name) {
+	case "bool", "int8", "uint8":
+		return sz.basic(1, 1), nil
+	case "int16", "uint16":
+		return sz.basic(2, 2), nil
+	case "int32", "uint32", "float32":
+		return sz.basic(4, 4), nil
+	case "int64", "uint64", "float64":
+		return sz.basic(8, 8), nil
+	case "complex64":
+		return sz.basic(8, 4), nil
+	case "complex128":
+		return sz.basic(16, 8), nil
+	case "int", "uint", "uintptr":
+		return sz.basic(word, word), nil
+	case "string":
+		return sz.basic(2*word, word), nil
+	}
+	return nil, fmt.Errorf("cannot determine the size of %s", name)
+}
+
+// basic returns the layout of a non-composite type limiting its alignment to the maximum one
+func (sz *sizer) basic(size int64, align int64) *Layout {
+	if align > sz.sizes.MaxAlign {
+		align = sz.sizes.MaxAlign
+	}
+	return &Layout{
+		Size:  size,
+		Align: align,
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func alignTo(x int64, a int64) int64 {
+	if a <= 1 {
+		return x
+	}
+	return (x + a - 1) / a * a
+}
@@ -0,0 +1,427 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+type Options struct {
+	// Filename returns the name of the page of a Go package. It defaults to
+	// `<import path>/<package name>.md`. Links between pages use relative paths.
+	Filename func(importPath string, packageName string) string
+
+	// IndexFilename returns the name of the index page of a module, defaults to `<module>/index.md`
+	IndexFilename func(module string) string
+
+	// IncludeUnexported also documents unexported declarations and struct fields
+	IncludeUnexported bool
+}
+
+// Page is a generated Markdown document
+type Page struct {
+	Filename string
+	Title    string
+	Content  string
+}
+
+type generator struct {
+	opts     Options
+	ps       *parser.PackageSet
+	packages map[string]*packageInfo
+}
+
+type packageInfo struct {
+	importPath string
+	name       string
+	module     string
+	filename   string
+	decls      []*parser.ParsedDeclaration
+}
+
+// segment is a part of a rendered type, named types are linked to their documentation
+type segment struct {
+	text string
+	link string
+}
+
+// -----------------------------------------------------------------------------
+
+// Generate renders the declarations of each package of the set to a Markdown page, along with an
+// index page for each module. References must be resolved before calling this function so named
+// types can be linked to their documentation.
+func Generate(ps *parser.PackageSet, opts Options) []*Page {
+	if opts.Filename == nil {
+		opts.Filename = func(importPath string, packageName string) string {
+			return importPath + "/" + packageName + ".md"
+		}
+	}
+	if opts.IndexFilename == nil {
+		opts.IndexFilename = func(module string) string {
+			return module + "/index.md"
+		}
+	}
+
+	g := generator{
+		opts:     opts,
+		ps:       ps,
+		packages: make(map[string]*packageInfo),
+	}
+
+	importPaths := make([]string, 0, len(ps.Packages))
+	for importPath, files := range ps.Packages {
+		if len(files) == 0 {
+			continue
+		}
+		pkg := &packageInfo{
+			importPath: importPath,
+			name:       files[0].Package,
+			module:     files[0].Module.Name,
+			filename:   opts.Filename(importPath, files[0].Package),
+			decls:      make([]*parser.ParsedDeclaration, 0),
+		}
+		for _, pf := range files {
+			for pdIdx := range pf.Declarations {
+				pd := &pf.Declarations[pdIdx]
+				if opts.IncludeUnexported || parser.IsPublic(pd.Name) {
+					pkg.decls = append(pkg.decls, pd)
+				}
+			}
+		}
+		g.packages[importPath] = pkg
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+
+	pages := make([]*Page, 0, len(importPaths)+1)
+	modules := make(map[string][]*packageInfo)
+	moduleNames := make([]string, 0)
+	for _, importPath := range importPaths {
+		pkg := g.packages[importPath]
+		pages = append(pages, g.packagePage(pkg))
+
+		if _, ok := modules[pkg.module]; !ok {
+			moduleNames = append(moduleNames, pkg.module)
+		}
+		modules[pkg.module] = append(modules[pkg.module], pkg)
+	}
+
+	for _, module := range moduleNames {
+		pages = append(pages, g.indexPage(module, modules[module]))
+	}
+
+	// Done
+	return pages
+}
+
+// -----------------------------------------------------------------------------
+
+func (g *generator) indexPage(module string, pkgs []*packageInfo) *Page {
+	filename := g.opts.IndexFilename(module)
+
+	sb := strings.Builder{}
+	sb.WriteString("# " + module + "\n\n")
+	sb.WriteString("| Package | Import path | Declarations |\n")
+	sb.WriteString("| --- | --- | --- |\n")
+	for _, pkg := range pkgs {
+		sb.WriteString(fmt.Sprintf("| [%s](%s) | `%s` | %d |\n", pkg.name, codegen.RelativePath(filename, pkg.filename),
+			pkg.importPath, len(pkg.decls)))
+	}
+
+	return &Page{
+		Filename: filename,
+		Title:    module,
+		Content:  sb.String(),
+	}
+}
+
+func (g *generator) packagePage(pkg *packageInfo) *Page {
+	sb := strings.Builder{}
+	sb.WriteString("# Package " + pkg.name + "\n\n")
+	sb.WriteString("`import \"" + pkg.importPath + "\"`\n")
+
+	if len(pkg.decls) > 0 {
+		sb.WriteString("\n")
+		for _, pd := range pkg.decls {
+			sb.WriteString("* [" + pd.Name + "](#" + anchor(pd.Name) + ")\n")
+		}
+	}
+
+	for _, pd := range pkg.decls {
+		sb.WriteString("\n## " + pd.Name + "\n\n")
+		if len(pd.Doc) > 0 {
+			sb.WriteString(pd.Doc + "\n\n")
+		}
+		sb.WriteString("```go\n" + declarationSignature(pd) + "\n```\n")
+
+		if ps, ok := pd.Type.(*parser.ParsedStruct); ok {
+			if table := g.fieldsTable(ps, pkg); len(table) > 0 {
+				sb.WriteString("\n" + table)
+			}
+		}
+		if values := g.ps.EnumValues(pd); len(values) > 0 {
+			sb.WriteString("\n| Constant | Value |\n")
+			sb.WriteString("| --- | --- |\n")
+			for _, pc := range values {
+				value := pc.Value
+				if pc.ParsedValue != nil {
+					value = pc.ParsedValue.ExactString()
+				}
+				sb.WriteString("| `" + pc.Name + "` | " + codeSpan(value) + " |\n")
+			}
+		}
+	}
+
+	return &Page{
+		Filename: pkg.filename,
+		Title:    "Package " + pkg.name,
+		Content:  sb.String(),
+	}
+}
+
+// fieldsTable returns a table with the name, type, JSON name, tags and doc comment of each field
+func (g *generator) fieldsTable(ps *parser.ParsedStruct, pkg *packageInfo) string {
+	sb := strings.Builder{}
+	rows := 0
+	for fieldIdx := range ps.Fields {
+		field := &ps.Fields[fieldIdx]
+
+		for _, name := range field.Identifiers() {
+			if !g.opts.IncludeUnexported && !parser.IsPublic(name) {
+				continue
+			}
+			if rows == 0 {
+				sb.WriteString("| Field | Type | JSON | Tags | Description |\n")
+				sb.WriteString("| --- | --- | --- | --- | --- |\n")
+			}
+			rows += 1
+
+			sb.WriteString("| " + name)
+			if len(field.Names) == 0 {
+				sb.WriteString(" _(embedded)_")
+			}
+			sb.WriteString(" | " + g.typeCell(field.Type, pkg))
+			sb.WriteString(" | " + jsonName(field, name))
+			sb.WriteString(" | " + tagSummary(field.Tags))
+			sb.WriteString(" | " + escapeCell(strings.ReplaceAll(field.Doc, "\n", " ")) + " |\n")
+		}
+	}
+	return sb.String()
+}
+
+// typeCell renders a type as code spans where named types link to their documentation
+func (g *generator) typeCell(t parser.ParsedType, pkg *packageInfo) string {
+	segments := g.typeSegments(t, pkg, make([]segment, 0))
+
+	sb := strings.Builder{}
+	text := ""
+	flush := func() {
+		if len(text) > 0 {
+			sb.WriteString(codeSpan(text))
+			text = ""
+		}
+	}
+	for _, seg := range segments {
+		if len(seg.link) == 0 {
+			text += seg.text
+			continue
+		}
+		flush()
+		sb.WriteString("[" + codeSpan(seg.text) + "](" + seg.link + ")")
+	}
+	flush()
+	return sb.String()
+}
+
+func (g *generator) typeSegments(t parser.ParsedType, pkg *packageInfo, segments []segment) []segment {
+	switch tType := t.(type) {
+	case *parser.ParsedNonNativeType:
+		return append(segments, segment{
+			text: tType.Name,
+			link: g.declarationLink(tType.Ref, pkg),
+		})
+
+	case *parser.ParsedPointer:
+		segments = append(segments, segment{
+			text: "*",
+		})
+		return g.typeSegments(tType.ToType, pkg, segments)
+
+	case *parser.ParsedArray:
+		segments = append(segments, segment{
+			text: "[" + tType.Size + "]",
+		})
+		return g.typeSegments(tType.ValueType, pkg, segments)
+
+	case *parser.ParsedMap:
+		segments = append(segments, segment{
+			text: "map[",
+		})
+		segments = g.typeSegments(tType.KeyType, pkg, segments)
+		segments = append(segments, segment{
+			text: "]",
+		})
+		return g.typeSegments(tType.ValueType, pkg, segments)
+
+	case *parser.ParsedIndex:
+		segments = g.typeSegments(tType.Type, pkg, segments)
+		for idx, index := range tType.Indexes {
+			sep := ", "
+			if idx == 0 {
+				sep = "["
+			}
+			segments = append(segments, segment{
+				text: sep,
+			})
+			segments = g.typeSegments(index, pkg, segments)
+		}
+		return append(segments, segment{
+			text: "]",
+		})
+	}
+
+	return append(segments, segment{
+		text: parser.Render(t, nil),
+	})
+}
+
+// declarationLink returns the link to the documentation of a declaration or an empty string if it
+// is not documented
+func (g *generator) declarationLink(pd *parser.ParsedDeclaration, from *packageInfo) string {
+	if pd == nil {
+		return ""
+	}
+	pf := pd.File()
+	if pf == nil {
+		return ""
+	}
+	target, ok := g.packages[pf.Module.FullName()]
+	if !ok {
+		return ""
+	}
+	found := false
+	for _, other := range target.decls {
+		if other == pd {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ""
+	}
+
+	if target == from {
+		return "#" + anchor(pd.Name)
+	}
+	return codegen.RelativePath(from.filename, target.filename) + "#" + anchor(pd.Name)
+}
+
+// -----------------------------------------------------------------------------
+
+// declarationSignature returns the first line of the declaration, like `type Page[T any] struct`
+func declarationSignature(pd *parser.ParsedDeclaration) string {
+	sb := strings.Builder{}
+	sb.WriteString("type " + pd.Name)
+	if len(pd.TypeParams) > 0 {
+		sb.WriteString("[")
+		for idx := range pd.TypeParams {
+			tp := &pd.TypeParams[idx]
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(strings.Join(tp.Names, ", ") + " " + parser.Render(tp.Type, nil))
+		}
+		sb.WriteString("]")
+	}
+	if pd.IsAlias {
+		sb.WriteString(" =")
+	}
+
+	if _, ok := pd.Type.(*parser.ParsedStruct); ok {
+		// Fields are listed in a table
+		sb.WriteString(" struct")
+	} else {
+		sb.WriteString(" " + parser.Render(pd.Type, nil))
+	}
+	return sb.String()
+}
+
+// jsonName returns the name of the field in JSON documents following the encoding/json rules
+func jsonName(field *parser.ParsedField, name string) string {
+	tag, _ := field.Tags.GetTag("json")
+	if tag == "-" {
+		return "-"
+	}
+	tagName, _ := codegen.SplitTag(tag)
+	if len(tagName) > 0 {
+		return codeSpan(tagName)
+	}
+	if len(field.Names) == 0 && parser.IsStruct(embeddedType(field.Type)) {
+		return "_(inlined)_"
+	}
+	if !parser.IsPublic(name) {
+		return ""
+	}
+	return codeSpan(name)
+}
+
+// tagSummary returns the tags of a field sorted by key, like `db:"id" json:"id,omitempty"`
+func tagSummary(tags parser.ParsedTags) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+":"+fmt.Sprintf("%q", string(tags[key])))
+	}
+	return codeSpan(strings.Join(parts, " "))
+}
+
+func embeddedType(t parser.ParsedType) parser.ParsedType {
+	if pp, ok := parser.Unalias(t).(*parser.ParsedPointer); ok {
+		return pp.ToType
+	}
+	return t
+}
+
+// codeSpan returns the text as an inline code span that can be used inside a table cell
+func codeSpan(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + escapeCell(text) + fence
+}
+
+// escapeCell escapes the pipes that would split a table cell
+func escapeCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+// anchor returns the anchor GitHub assigns to a heading
+func anchor(heading string) string {
+	sb := strings.Builder{}
+	for _, ch := range strings.ToLower(heading) {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9', ch == '_', ch == '-':
+			sb.WriteRune(ch)
+		case ch == ' ':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
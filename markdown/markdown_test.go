@@ -0,0 +1,248 @@
+package markdown_test
+
+import (
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/markdown"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	common, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package common
+
+// Money is an amount in cents
+type Money int64
+`,
+		Filename: "common.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "common",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	orders, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package orders
+
+import (
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+type Status string
+
+const (
+	Pending Status = "pending"
+	Paid    Status = "paid"
+)
+
+// Order is sent to the billing service
+type Order struct {
+	Base
+	ID     string                 ` + "`json:\"id\" db:\"id\"`" + ` // Unique identifier
+	Total  common.Money           ` + "`json:\"total\"`" + `
+	Lines  []*Line                ` + "`json:\"lines,omitempty\"`" + `
+	Labels map[Status]string      ` + "`json:\"-\"`" + `
+	secret string
+}
+
+type Base struct {
+	Version int
+}
+
+type Line struct {
+	SKU string // Stock keeping unit, format A|B
+}
+`,
+		Filename: "orders.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "orders",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{common, orders})
+	ps.ResolveReferences()
+
+	pages := markdown.Generate(ps, markdown.Options{})
+	if len(pages) != 3 {
+		t.Fatalf("unexpected number of pages [count=%d]", len(pages))
+	}
+
+	expected := []string{
+		"github.com/mxmauro/gofile-parser-test/common/common.md",
+		"github.com/mxmauro/gofile-parser-test/orders/orders.md",
+		"github.com/mxmauro/gofile-parser-test/index.md",
+	}
+	for idx, page := range pages {
+		if page.Filename != expected[idx] {
+			t.Fatalf("unexpected page filename [expected=%v] [got=%v]", expected[idx], page.Filename)
+		}
+	}
+
+	expectedContent := "# Package orders\n" +
+		"\n" +
+		"`import \"github.com/mxmauro/gofile-parser-test/orders\"`\n" +
+		"\n" +
+		"* [Status](#status)\n" +
+		"* [Order](#order)\n" +
+		"* [Base](#base)\n" +
+		"* [Line](#line)\n" +
+		"\n" +
+		"## Status\n" +
+		"\n" +
+		"```go\n" +
+		"type Status string\n" +
+		"```\n" +
+		"\n" +
+		"| Constant | Value |\n" +
+		"| --- | --- |\n" +
+		"| `Pending` | `\"pending\"` |\n" +
+		"| `Paid` | `\"paid\"` |\n" +
+		"\n" +
+		"## Order\n" +
+		"\n" +
+		"Order is sent to the billing service\n" +
+		"\n" +
+		"```go\n" +
+		"type Order struct\n" +
+		"```\n" +
+		"\n" +
+		"| Field | Type | JSON | Tags | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| Base _(embedded)_ | [`Base`](#base) | _(inlined)_ |  |  |\n" +
+		"| ID | `string` | `id` | `db:\"id\" json:\"id\"` | Unique identifier |\n" +
+		"| Total | [`common.Money`](../common/common.md#money) | `total` | `json:\"total\"` |  |\n" +
+		"| Lines | `[]*`[`Line`](#line) | `lines` | `json:\"lines,omitempty\"` |  |\n" +
+		"| Labels | `map[`[`Status`](#status)`]string` | - | `json:\"-\"` |  |\n" +
+		"\n" +
+		"## Base\n" +
+		"\n" +
+		"```go\n" +
+		"type Base struct\n" +
+		"```\n" +
+		"\n" +
+		"| Field | Type | JSON | Tags | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| Version | `int` | `Version` |  |  |\n" +
+		"\n" +
+		"## Line\n" +
+		"\n" +
+		"```go\n" +
+		"type Line struct\n" +
+		"```\n" +
+		"\n" +
+		"| Field | Type | JSON | Tags | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| SKU | `string` | `SKU` |  | Stock keeping unit, format A\\|B |\n"
+	if pages[1].Content != expectedContent {
+		t.Fatalf("unexpected package page\n[expected]\n%v\n[got]\n%v", expectedContent, pages[1].Content)
+	}
+
+	expectedIndex := "# github.com/mxmauro/gofile-parser-test\n" +
+		"\n" +
+		"| Package | Import path | Declarations |\n" +
+		"| --- | --- | --- |\n" +
+		"| [common](common/common.md) | `github.com/mxmauro/gofile-parser-test/common` | 1 |\n" +
+		"| [orders](orders/orders.md) | `github.com/mxmauro/gofile-parser-test/orders` | 4 |\n"
+	if pages[2].Content != expectedIndex {
+		t.Fatalf("unexpected index page\n[expected]\n%v\n[got]\n%v", expectedIndex, pages[2].Content)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package lists
+
+import (
+	"time"
+)
+
+type Node[T any] struct {
+	Value T
+	Next  *Node[T]
+}
+
+type List struct {
+	Head    *Node[Item]
+	Self    *List
+	Owner   Account
+	Created time.Time
+}
+
+type Item struct {
+	Name string
+}
+`,
+		Filename: "lists.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	pages := markdown.Generate(ps, markdown.Options{})
+	if len(pages) != 2 {
+		t.Fatalf("unexpected number of pages [count=%d]", len(pages))
+	}
+
+	expectedContent := "# Package lists\n" +
+		"\n" +
+		"`import \"github.com/mxmauro/gofile-parser-test\"`\n" +
+		"\n" +
+		"* [Node](#node)\n" +
+		"* [List](#list)\n" +
+		"* [Item](#item)\n" +
+		"\n" +
+		"## Node\n" +
+		"\n" +
+		"```go\n" +
+		"type Node[T any] struct\n" +
+		"```\n" +
+		"\n" +
+		"| Field | Type | JSON | Tags | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| Value | `T` | `Value` |  |  |\n" +
+		"| Next | `*`[`Node`](#node)`[T]` | `Next` |  |  |\n" +
+		"\n" +
+		"## List\n" +
+		"\n" +
+		"```go\n" +
+		"type List struct\n" +
+		"```\n" +
+		"\n" +
+		"| Field | Type | JSON | Tags | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| Head | `*`[`Node`](#node)`[`[`Item`](#item)`]` | `Head` |  |  |\n" +
+		"| Self | `*`[`List`](#list) | `Self` |  |  |\n" +
+		"| Owner | `Account` | `Owner` |  |  |\n" +
+		"| Created | `time.Time` | `Created` |  |  |\n" +
+		"\n" +
+		"## Item\n" +
+		"\n" +
+		"```go\n" +
+		"type Item struct\n" +
+		"```\n" +
+		"\n" +
+		"| Field | Type | JSON | Tags | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| Name | `string` | `Name` |  |  |\n"
+	if pages[0].Content != expectedContent {
+		t.Fatalf("unexpected package page\n[expected]\n%v\n[got]\n%v", expectedContent, pages[0].Content)
+	}
+}
@@ -0,0 +1,395 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+)
+
+// -----------------------------------------------------------------------------
+
+// MethodSetEntry is a method that belongs to the method set of a type, either declared directly or
+// promoted from an embedded field.
+type MethodSetEntry struct {
+	Name   string
+	Func   *ParsedFunction
+	Method *ParsedMethod // Nil for methods declared in interfaces
+	Path   []string      // Embedded fields traversed to reach the method, e.g. ["Base"]
+}
+
+// MethodMismatch describes a method required by an interface that a type does not provide
+type MethodMismatch struct {
+	Name            string
+	Want            *ParsedFunction
+	Have            *ParsedFunction // Nil if the method does not exist
+	PointerReceiver bool            // True if the method exists but only for the pointer type
+}
+
+// Implementation is a declaration that satisfies an interface
+type Implementation struct {
+	Declaration *ParsedDeclaration
+	Pointer     bool // True if only the pointer to the declared type satisfies the interface
+}
+
+type methodSetNode struct {
+	t       ParsedType
+	path    []string
+	pointer bool
+}
+
+type methodCandidate struct {
+	entry *MethodSetEntry // Nil for fields and methods not available in the method set
+}
+
+// -----------------------------------------------------------------------------
+
+// MethodSet returns the method set of the given type sorted by name, following Go's rules: the
+// method set of a declared type T contains the methods with value receivers and the one of *T also
+// includes the methods with pointer receivers. Methods of embedded fields are promoted unless a
+// field or method with the same name exists at a lower depth, and ambiguous names are excluded.
+// The method set of an interface contains its methods, including the ones of embedded interfaces.
+// References must be resolved before calling this function.
+func MethodSet(t ParsedType) []MethodSetEntry {
+	t = Unalias(t)
+	pointer := false
+	if pp, ok := t.(*ParsedPointer); ok {
+		pointer = true
+		t = Unalias(pp.ToType)
+	}
+
+	if pi, ok := Underlying(t).(*ParsedInterface); ok {
+		if pointer {
+			return make([]MethodSetEntry, 0) // Pointers to interfaces have no methods
+		}
+		return interfaceMethodSet(pi, make(map[*ParsedInterface]struct{}))
+	}
+
+	entries := make([]MethodSetEntry, 0)
+	blocked := make(map[string]struct{})
+	seen := make(map[string]struct{})
+
+	current := []methodSetNode{
+		{
+			t:       t,
+			path:    make([]string, 0),
+			pointer: pointer,
+		},
+	}
+	for len(current) > 0 {
+		candidates := make(map[string][]methodCandidate)
+		order := make([]string, 0)
+		next := make([]methodSetNode, 0)
+
+		addCandidate := func(name string, entry *MethodSetEntry) {
+			if _, ok := candidates[name]; !ok {
+				order = append(order, name)
+			}
+			candidates[name] = append(candidates[name], methodCandidate{
+				entry: entry,
+			})
+		}
+
+		for _, node := range current {
+			for _, entry := range declaredMethods(node.t) {
+				if entry.Method.PointerReceiver && (!node.pointer) {
+					addCandidate(entry.Name, nil) // The name is taken even if the method is not available
+					continue
+				}
+				entry := entry
+				entry.Path = node.path
+				addCandidate(entry.Name, &entry)
+			}
+
+			switch u := Underlying(node.t).(type) {
+			case *ParsedStruct:
+				for _, field := range u.Fields {
+					for _, name := range field.Identifiers() {
+						if len(name) > 0 {
+							addCandidate(name, nil)
+						}
+					}
+					if len(field.Names) > 0 || len(field.ImplicitName) == 0 {
+						continue
+					}
+
+					// Queue embedded types for the next depth
+					fieldType := Unalias(field.Type)
+					isPointer := false
+					if pp, ok := fieldType.(*ParsedPointer); ok {
+						isPointer = true
+						fieldType = Unalias(pp.ToType)
+					}
+					key := methodSetKey(fieldType)
+					if len(key) == 0 {
+						continue
+					}
+					if _, ok := seen[key]; ok {
+						continue // Already processed at a lower depth
+					}
+					next = append(next, methodSetNode{
+						t:       fieldType,
+						path:    append(append(make([]string, 0, len(node.path)+1), node.path...), field.ImplicitName),
+						pointer: node.pointer || isPointer,
+					})
+				}
+
+			case *ParsedInterface:
+				for _, entry := range interfaceMethodSet(u, make(map[*ParsedInterface]struct{})) {
+					entry := entry
+					entry.Path = node.path
+					addCandidate(entry.Name, &entry)
+				}
+			}
+		}
+
+		for _, name := range order {
+			if _, ok := blocked[name]; ok {
+				continue // Shadowed by a field or method at a lower depth or ambiguous
+			}
+			blocked[name] = struct{}{}
+			if len(candidates[name]) == 1 && candidates[name][0].entry != nil {
+				entries = append(entries, *candidates[name][0].entry)
+			}
+		}
+
+		// Named types queued for the next depth must not be processed again deeper
+		for _, node := range next {
+			seen[methodSetKey(node.t)] = struct{}{}
+		}
+
+		current = next
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	// Done
+	return entries
+}
+
+// MethodSet returns the method set of the declared type, or of a pointer to it if pointer is true.
+// See the MethodSet function for details.
+func (pd *ParsedDeclaration) MethodSet(pointer bool) []MethodSetEntry {
+	var t ParsedType = &ParsedNonNativeType{
+		Name: pd.Name,
+		Ref:  pd,
+	}
+	if pointer {
+		t = &ParsedPointer{
+			ToType: t,
+		}
+	}
+	return MethodSet(t)
+}
+
+// Implements returns true if the type satisfies the interface. If not, the returned list contains
+// the methods that are missing or have a different signature. Only methods are checked, type
+// terms of constraint interfaces are ignored.
+func Implements(t ParsedType, iface ParsedType) (bool, []MethodMismatch) {
+	pi, ok := Underlying(Unalias(iface)).(*ParsedInterface)
+	if !ok {
+		return false, nil
+	}
+
+	have := make(map[string]MethodSetEntry)
+	for _, entry := range MethodSet(t) {
+		have[entry.Name] = entry
+	}
+
+	// Used to report methods that are only available for the pointer type
+	pointerHave := make(map[string]MethodSetEntry)
+	if _, isPointer := Unalias(t).(*ParsedPointer); !isPointer && !IsInterface(t) {
+		for _, entry := range MethodSet(&ParsedPointer{ToType: t}) {
+			pointerHave[entry.Name] = entry
+		}
+	}
+
+	mismatches := make([]MethodMismatch, 0)
+	for _, want := range interfaceMethodSet(pi, make(map[*ParsedInterface]struct{})) {
+		if entry, ok := have[want.Name]; ok {
+			if !Identical(entry.Func, want.Func) {
+				mismatches = append(mismatches, MethodMismatch{
+					Name: want.Name,
+					Want: want.Func,
+					Have: entry.Func,
+				})
+			}
+			continue
+		}
+
+		mismatch := MethodMismatch{
+			Name: want.Name,
+			Want: want.Func,
+		}
+		if entry, ok := pointerHave[want.Name]; ok && Identical(entry.Func, want.Func) {
+			mismatch.PointerReceiver = true
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+
+	// Done
+	return len(mismatches) == 0, mismatches
+}
+
+// FindImplementations returns the non-generic, non-interface declarations of the given files that
+// satisfy the interface, either directly or through a pointer.
+func FindImplementations(parsedFiles []*ParsedFile, iface ParsedType) []Implementation {
+	impls := make([]Implementation, 0)
+	for _, pf := range parsedFiles {
+		for pdIdx := range pf.Declarations {
+			pd := &pf.Declarations[pdIdx]
+			if len(pd.TypeParams) > 0 || pd.IsAlias {
+				continue
+			}
+
+			t := &ParsedNonNativeType{
+				Name: pd.Name,
+				Ref:  pd,
+			}
+			if IsInterface(t) {
+				continue
+			}
+			if ok, _ := Implements(t, iface); ok {
+				impls = append(impls, Implementation{
+					Declaration: pd,
+				})
+			} else if ok, _ = Implements(&ParsedPointer{ToType: t}, iface); ok {
+				impls = append(impls, Implementation{
+					Declaration: pd,
+					Pointer:     true,
+				})
+			}
+		}
+	}
+	return impls
+}
+
+// -----------------------------------------------------------------------------
+
+// declaredMethods returns the methods declared for a named type. For generic instances, the
+// receiver type parameters are replaced by the type arguments.
+func declaredMethods(t ParsedType) []MethodSetEntry {
+	entries := make([]MethodSetEntry, 0)
+
+	switch tType := t.(type) {
+	case *ParsedNonNativeType:
+		if tType.Ref == nil {
+			break
+		}
+		for _, pm := range tType.Ref.Methods {
+			if pm.Type == nil {
+				continue
+			}
+			entries = append(entries, MethodSetEntry{
+				Name:   pm.Name,
+				Func:   pm.Type,
+				Method: pm,
+			})
+		}
+
+	case *ParsedIndex:
+		pnnt, ok := tType.Type.(*ParsedNonNativeType)
+		if !ok || pnnt.Ref == nil {
+			break
+		}
+		inst := instantiator{
+			instances: make(map[string]*ParsedDeclaration),
+		}
+		for _, pm := range pnnt.Ref.Methods {
+			if pm.Type == nil {
+				continue
+			}
+
+			args := make(map[typeParamKey]ParsedType)
+			for tpIdx := range pm.ReceiverTypeParams {
+				tp := &pm.ReceiverTypeParams[tpIdx]
+				for _, name := range tp.Names {
+					if len(args) < len(tType.Indexes) {
+						args[typeParamKey{param: tp, name: name}] = tType.Indexes[len(args)]
+					}
+				}
+			}
+			// Methods whose signature cannot be instantiated are skipped
+			t, err := inst.substitute(pm.Type, args)
+			if err != nil {
+				continue
+			}
+			pf, _ := t.(*ParsedFunction)
+			if pf == nil {
+				continue
+			}
+			entries = append(entries, MethodSetEntry{
+				Name:   pm.Name,
+				Func:   pf,
+				Method: pm,
+			})
+		}
+	}
+
+	// Done
+	return entries
+}
+
+// interfaceMethodSet returns the methods of an interface including the ones of embedded interfaces
+func interfaceMethodSet(pi *ParsedInterface, visited map[*ParsedInterface]struct{}) []MethodSetEntry {
+	entries := make([]MethodSetEntry, 0)
+	if _, ok := visited[pi]; ok {
+		return entries
+	}
+	visited[pi] = struct{}{}
+
+	names := make(map[string]struct{})
+	add := func(entry MethodSetEntry) {
+		if _, ok := names[entry.Name]; !ok {
+			names[entry.Name] = struct{}{}
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, member := range pi.Methods {
+		if len(member.Names) == 0 {
+			if embedded, ok := Underlying(Unalias(member.Type)).(*ParsedInterface); ok {
+				for _, entry := range interfaceMethodSet(embedded, visited) {
+					add(entry)
+				}
+			}
+			continue
+		}
+
+		pf, ok := member.Type.(*ParsedFunction)
+		if !ok {
+			continue
+		}
+		for _, name := range member.Names {
+			add(MethodSetEntry{
+				Name: name,
+				Func: pf,
+				Path: make([]string, 0),
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	// Done
+	return entries
+}
+
+// methodSetKey returns a key that identifies a named type or an empty string if the type cannot
+// have methods
+func methodSetKey(t ParsedType) string {
+	switch tType := t.(type) {
+	case *ParsedNonNativeType:
+		if tType.Ref != nil {
+			return fmt.Sprintf("%p", tType.Ref)
+		}
+
+	case *ParsedIndex:
+		if pnnt, ok := tType.Type.(*ParsedNonNativeType); ok && pnnt.Ref != nil {
+			return fmt.Sprintf("%p", pnnt.Ref) + Render(tType, nil)
+		}
+	}
+	return ""
+}
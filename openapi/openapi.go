@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/jsonschema"
+)
+
+// -----------------------------------------------------------------------------
+
+// DefaultTagKey is the struct tag key used to customize the schema of a field, for example,
+// `openapi:"format=uuid,example=abc"`
+const DefaultTagKey = "openapi"
+
+type Options struct {
+	// Packages, if set, is used to find the constants declared with a named type, which are
+	// emitted as the enum values of the type.
+	Packages *parser.PackageSet
+
+	// TagKey is the struct tag key that contains the field overrides, defaults to DefaultTagKey
+	TagKey string
+
+	// DisallowAdditionalProperties sets additionalProperties to false in the schemas of structs
+	DisallowAdditionalProperties bool
+}
+
+// Document contains the `components` section of an OpenAPI 3.1 document
+type Document struct {
+	Components Components `json:"components"`
+}
+
+type Components struct {
+	Schemas map[string]*jsonschema.Schema `json:"schemas"`
+}
+
+// -----------------------------------------------------------------------------
+
+// Generate creates the component schemas of the given declarations and the ones they reference.
+// Named types are referenced with `$ref` instead of being inlined. References must be resolved
+// before calling this function.
+//
+// Field schemas can be customized with the following tag properties: format, description, title,
+// pattern, example, minimum, maximum, minLength, maxLength, deprecated, readOnly and writeOnly.
+func Generate(decls []*parser.ParsedDeclaration, opts Options) (*Document, error) {
+	if len(opts.TagKey) == 0 {
+		opts.TagKey = DefaultTagKey
+	}
+
+	g := jsonschema.NewGenerator(jsonschema.Options{
+		DefinitionsPath:              "#/components/schemas/",
+		RefNamedTypes:                true,
+		DisallowAdditionalProperties: opts.DisallowAdditionalProperties,
+		Packages:                     opts.Packages,
+		FieldHook: func(field *parser.ParsedField, s *jsonschema.Schema) error {
+			return applyOverrides(field, s, opts.TagKey)
+		},
+	})
+
+	inlined := make(map[string]*jsonschema.Schema)
+	for _, pd := range decls {
+		s, err := g.Declaration(pd)
+		if err != nil {
+			return nil, err
+		}
+		if len(s.Ref) == 0 {
+			// Types with a well-known or custom representation are not stored as definitions
+			inlined[pd.Name] = s
+		}
+	}
+
+	doc := &Document{
+		Components: Components{
+			Schemas: make(map[string]*jsonschema.Schema),
+		},
+	}
+	for name, s := range g.Definitions() {
+		doc.Components.Schemas[name] = s
+	}
+	for name, s := range inlined {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			doc.Components.Schemas[name] = s
+		}
+	}
+
+	// Done
+	return doc, nil
+}
+
+// JSON returns the document encoded as indented JSON
+func (doc *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// YAML returns the document encoded as YAML
+func (doc *Document) YAML() ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(data)
+}
+
+// -----------------------------------------------------------------------------
+
+func applyOverrides(field *parser.ParsedField, s *jsonschema.Schema, tagKey string) error {
+	tag, ok := field.Tags.GetTag(tagKey)
+	if !ok {
+		return nil
+	}
+
+	for _, key := range []string{"format", "description", "title", "pattern"} {
+		value, found := tag.GetProperty(key)
+		if !found {
+			continue
+		}
+		switch key {
+		case "format":
+			s.Format = value
+		case "description":
+			s.Description = value
+		case "title":
+			s.Title = value
+		case "pattern":
+			s.Pattern = value
+		}
+	}
+
+	if value, found := tag.GetProperty("example"); found {
+		s.Examples = []interface{}{exampleValue(value, s)}
+	}
+
+	for _, key := range []string{"minimum", "maximum"} {
+		value, found := tag.GetProperty(key)
+		if !found {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %s", key, value)
+		}
+		if key == "minimum" {
+			s.Minimum = &f
+		} else {
+			s.Maximum = &f
+		}
+	}
+
+	for _, key := range []string{"minLength", "maxLength"} {
+		value, found := tag.GetProperty(key)
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s value %s", key, value)
+		}
+		if key == "minLength" {
+			s.MinLength = &n
+		} else {
+			s.MaxLength = &n
+		}
+	}
+
+	if tag.HasProperty("deprecated") {
+		s.Deprecated = tag.GetBoolProperty("deprecated")
+	}
+	if tag.HasProperty("readOnly") {
+		s.ReadOnly = tag.GetBoolProperty("readOnly")
+	}
+	if tag.HasProperty("writeOnly") {
+		s.WriteOnly = tag.GetBoolProperty("writeOnly")
+	}
+
+	// Done
+	return nil
+}
+
+// exampleValue converts the example given in a tag to a JSON value. Examples of string schemas are
+// kept as strings, other ones are decoded as JSON if possible.
+func exampleValue(value string, s *jsonschema.Schema) interface{} {
+	for _, t := range s.Type {
+		if t == "string" {
+			return value
+		}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err == nil {
+		return v
+	}
+	return value
+}
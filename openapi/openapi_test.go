@@ -0,0 +1,264 @@
+package openapi_test
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/openapi"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+// Status of an order
+type Status string
+
+const (
+	Pending Status = "pending"
+	Shipped Status = "shipped"
+)
+
+type Order struct {
+	ID     string  ` + "`json:\"id\" openapi:\"format=uuid,example=abc\"`" + `
+	Status Status  ` + "`json:\"status\"`" + `
+	Total  float64 ` + "`json:\"total\" openapi:\"minimum=0,example=9.5\"`" + `
+	Items  []Item  ` + "`json:\"items\"`" + `
+}
+
+type Item struct {
+	SKU string ` + "`json:\"sku\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	doc, err := openapi.Generate([]*parser.ParsedDeclaration{&pf.Declarations[1]}, openapi.Options{
+		Packages: ps,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate components [err=%v]", err)
+	}
+
+	data, err := doc.YAML()
+	if err != nil {
+		t.Fatalf("unable to encode components [err=%v]", err)
+	}
+	expected := `components:
+  schemas:
+    Item:
+      type: object
+      properties:
+        sku:
+          type: string
+      required:
+        - sku
+    Order:
+      type: object
+      properties:
+        id:
+          type: string
+          format: uuid
+          examples:
+            - abc
+        status:
+          $ref: "#/components/schemas/Status"
+        total:
+          type: number
+          minimum: 0
+          examples:
+            - 9.5
+        items:
+          type: array
+          items:
+            $ref: "#/components/schemas/Item"
+      required:
+        - id
+        - status
+        - total
+        - items
+    Status:
+      description: "Status of an order"
+      type: string
+      enum:
+        - pending
+        - shipped
+`
+	if string(data) != expected {
+		t.Fatalf("wrong components:\n%v", string(data))
+	}
+
+	if _, err = doc.JSON(); err != nil {
+		t.Fatalf("unable to encode components [err=%v]", err)
+	}
+}
+
+func TestYAMLScalars(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Version string
+
+const (
+	Released Version = "2024-01-01"
+	Hex      Version = "0x1F"
+	Grouped  Version = "1_000"
+	Signed   Version = "+1"
+	Time     Version = "12:30"
+	Latest   Version = "latest"
+	Tagged   Version = "v1.2"
+)
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{pf})
+	ps.ResolveReferences()
+
+	doc, err := openapi.Generate([]*parser.ParsedDeclaration{&pf.Declarations[0]}, openapi.Options{
+		Packages: ps,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate components [err=%v]", err)
+	}
+
+	data, err := doc.YAML()
+	if err != nil {
+		t.Fatalf("unable to encode components [err=%v]", err)
+	}
+	expected := `components:
+  schemas:
+    Version:
+      type: string
+      enum:
+        - "2024-01-01"
+        - "0x1F"
+        - "1_000"
+        - "+1"
+        - "12:30"
+        - latest
+        - v1.2
+`
+	if string(data) != expected {
+		t.Fatalf("wrong components:\n%v", string(data))
+	}
+}
+
+func TestInvalidTags(t *testing.T) {
+	for _, tc := range []struct {
+		tag      string
+		expected string
+	}{
+		{"minimum=low", "invalid minimum value low"},
+		{"maxLength=-1", "invalid maxLength value -1"},
+	} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content: `
+package main
+
+type Order struct {
+	Note string ` + "`json:\"note\" openapi:\"" + tc.tag + "\"`" + `
+}
+`,
+			Filename: "test.go",
+			Module: parser.Module{
+				Name: "github.com/mxmauro/gofile-parser-test",
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+		_, err = openapi.Generate([]*parser.ParsedDeclaration{&pf.Declarations[0]}, openapi.Options{})
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Fatalf("wrong error for tag %v: %v", tc.tag, err)
+		}
+	}
+}
+
+func TestRecursiveAndGeneric(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Page[T any] struct {
+	Items []T ` + "`json:\"items\"`" + `
+}
+
+type Node struct {
+	Children Page[Node] ` + "`json:\"children\"`" + `
+	Parent   *Node      ` + "`json:\"parent,omitempty\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	doc, err := openapi.Generate([]*parser.ParsedDeclaration{&pf.Declarations[1]}, openapi.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate components [err=%v]", err)
+	}
+
+	data, err := doc.YAML()
+	if err != nil {
+		t.Fatalf("unable to encode components [err=%v]", err)
+	}
+	expected := `components:
+  schemas:
+    Node:
+      type: object
+      properties:
+        children:
+          $ref: "#/components/schemas/Page_Node"
+        parent:
+          anyOf:
+            - $ref: "#/components/schemas/Node"
+            - type: "null"
+      required:
+        - children
+    Page_Node:
+      type: object
+      properties:
+        items:
+          type: array
+          items:
+            $ref: "#/components/schemas/Node"
+      required:
+        - items
+`
+	if string(data) != expected {
+		t.Fatalf("wrong components:\n%v", string(data))
+	}
+
+	_, err = openapi.Generate([]*parser.ParsedDeclaration{&pf.Declarations[0]}, openapi.Options{})
+	if err == nil || !strings.Contains(err.Error(), "generic declaration Page must be instantiated") {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// yamlNode is a JSON value that keeps the order of the object keys
+type yamlNode struct {
+	object bool
+	array  bool
+	keys   []string
+	items  []*yamlNode
+	scalar string
+}
+
+type yamlEncoder struct {
+	buf bytes.Buffer
+}
+
+// -----------------------------------------------------------------------------
+
+// jsonToYAML converts a JSON document to YAML keeping the order of the object keys
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	root, err := decodeYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := yamlEncoder{}
+	enc.writeRoot(root)
+	return enc.buf.Bytes(), nil
+}
+
+func decodeYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		n := &yamlNode{
+			keys:  make([]string, 0),
+			items: make([]*yamlNode, 0),
+		}
+		switch v {
+		case '{':
+			n.object = true
+			for dec.More() {
+				tok, err = dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := tok.(string)
+				if !ok {
+					return nil, errors.New("invalid object key")
+				}
+				item, err2 := decodeYAMLNode(dec)
+				if err2 != nil {
+					return nil, err2
+				}
+				n.keys = append(n.keys, key)
+				n.items = append(n.items, item)
+			}
+		case '[':
+			n.array = true
+			for dec.More() {
+				item, err2 := decodeYAMLNode(dec)
+				if err2 != nil {
+					return nil, err2
+				}
+				n.items = append(n.items, item)
+			}
+		default:
+			return nil, errors.New("unexpected delimiter")
+		}
+		// Consume the closing delimiter
+		if _, err = dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case string:
+		return &yamlNode{
+			scalar: yamlString(v),
+		}, nil
+
+	case json.Number:
+		return &yamlNode{
+			scalar: v.String(),
+		}, nil
+
+	case bool:
+		return &yamlNode{
+			scalar: strconv.FormatBool(v),
+		}, nil
+
+	case nil:
+		return &yamlNode{
+			scalar: "null",
+		}, nil
+	}
+
+	return nil, errors.New("unexpected token")
+}
+
+func (enc *yamlEncoder) writeRoot(n *yamlNode) {
+	switch {
+	case n.isEmptyCollection():
+		enc.buf.WriteString(n.emptyCollection() + "\n")
+	case n.object:
+		enc.writeObject(n, 0, "")
+	case n.array:
+		enc.writeArray(n, 0, "")
+	default:
+		enc.buf.WriteString(n.scalar + "\n")
+	}
+}
+
+// writeObject writes the members of an object. If firstPrefix is not empty, it is written instead
+// of the indentation of the first member, this is used for objects inside arrays.
+func (enc *yamlEncoder) writeObject(n *yamlNode, indent int, firstPrefix string) {
+	for idx, key := range n.keys {
+		if idx == 0 && len(firstPrefix) > 0 {
+			enc.buf.WriteString(firstPrefix)
+		} else {
+			enc.buf.WriteString(strings.Repeat(" ", indent))
+		}
+		enc.buf.WriteString(yamlString(key) + ":")
+		enc.writeValue(n.items[idx], indent)
+	}
+}
+
+func (enc *yamlEncoder) writeArray(n *yamlNode, indent int, firstPrefix string) {
+	for idx, item := range n.items {
+		prefix := strings.Repeat(" ", indent) + "- "
+		if idx == 0 && len(firstPrefix) > 0 {
+			prefix = firstPrefix + "- "
+		}
+
+		switch {
+		case item.isEmptyCollection():
+			enc.buf.WriteString(prefix + item.emptyCollection() + "\n")
+		case item.object:
+			enc.writeObject(item, indent+2, prefix)
+		case item.array:
+			enc.writeArray(item, indent+2, prefix)
+		default:
+			enc.buf.WriteString(prefix + item.scalar + "\n")
+		}
+	}
+}
+
+// writeValue writes the value of an object member after its key
+func (enc *yamlEncoder) writeValue(n *yamlNode, indent int) {
+	switch {
+	case n.isEmptyCollection():
+		enc.buf.WriteString(" " + n.emptyCollection() + "\n")
+	case n.object:
+		enc.buf.WriteString("\n")
+		enc.writeObject(n, indent+2, "")
+	case n.array:
+		enc.buf.WriteString("\n")
+		enc.writeArray(n, indent+2, "")
+	default:
+		enc.buf.WriteString(" " + n.scalar + "\n")
+	}
+}
+
+func (n *yamlNode) isEmptyCollection() bool {
+	return (n.object || n.array) && len(n.items) == 0
+}
+
+func (n *yamlNode) emptyCollection() string {
+	if n.object {
+		return "{}"
+	}
+	return "[]"
+}
+
+// -----------------------------------------------------------------------------
+
+// yamlString returns the string as a plain scalar if it is safe to do so, else it is quoted. JSON
+// quoted strings are valid YAML double-quoted scalars.
+func yamlString(s string) string {
+	if isPlainYAMLString(s) {
+		return s
+	}
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+func isPlainYAMLString(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	// Scalars starting with a digit or a sign can be resolved as numbers, dates or times, like
+	// 0x1F, 1_000 or 2024-01-01
+	if (s[0] >= '0' && s[0] <= '9') || s[0] == '+' || s[0] == '-' {
+		return false
+	}
+	for idx, ch := range s {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '_' || ch == '$' || ch == '/':
+		case (ch == '-' || ch == '#' || ch == '.') && idx > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// PackageSet groups a set of parsed files by package import path. It keeps an index of the
+// declarations and constants of each package so lookups do not need to scan the files. Files must
+// be added with Add for the index to be updated.
+type PackageSet struct {
+	Packages map[string][]*ParsedFile // Keyed by the module full name of the files
+
+	files        []*ParsedFile
+	declarations map[string]map[string]*ParsedDeclaration
+	constants    map[string]map[string]indexedConstant
+}
+
+type indexedConstant struct {
+	pf *ParsedFile
+	pc *ParsedConstant
+}
+
+// -----------------------------------------------------------------------------
+
+// NewPackageSet creates a package set from a list of parsed files
+func NewPackageSet(parsedFiles []*ParsedFile) *PackageSet {
+	ps := &PackageSet{
+		Packages:     make(map[string][]*ParsedFile),
+		files:        make([]*ParsedFile, 0, len(parsedFiles)),
+		declarations: make(map[string]map[string]*ParsedDeclaration),
+		constants:    make(map[string]map[string]indexedConstant),
+	}
+	for _, pf := range parsedFiles {
+		ps.Add(pf)
+	}
+	return ps
+}
+
+// Add adds a parsed file to the set and indexes its declarations and constants. If a name is
+// declared more than once in the same package, the first declaration is kept.
+func (ps *PackageSet) Add(pf *ParsedFile) {
+	importPath := pf.Module.FullName()
+	ps.Packages[importPath] = append(ps.Packages[importPath], pf)
+	ps.files = append(ps.files, pf)
+
+	decls, ok := ps.declarations[importPath]
+	if !ok {
+		decls = make(map[string]*ParsedDeclaration)
+		ps.declarations[importPath] = decls
+	}
+	for pdIdx := range pf.Declarations {
+		pd := &pf.Declarations[pdIdx]
+		if _, ok = decls[pd.Name]; !ok {
+			decls[pd.Name] = pd
+		}
+	}
+
+	consts, ok := ps.constants[importPath]
+	if !ok {
+		consts = make(map[string]indexedConstant)
+		ps.constants[importPath] = consts
+	}
+	for pcIdx := range pf.Constants {
+		pc := &pf.Constants[pcIdx]
+		if _, ok = consts[pc.Name]; !ok {
+			consts[pc.Name] = indexedConstant{
+				pf: pf,
+				pc: pc,
+			}
+		}
+	}
+}
+
+// Lookup returns the declaration with the given name inside the package with the specified import
+// path, or nil if not found.
+func (ps *PackageSet) Lookup(importPath string, name string) *ParsedDeclaration {
+	return ps.declarations[importPath][name]
+}
+
+// LookupConstant returns the constant with the given name inside the package with the specified
+// import path, or nil if not found.
+func (ps *PackageSet) LookupConstant(importPath string, name string) *ParsedConstant {
+	return ps.constants[importPath][name].pc
+}
+
+// Declarations returns the index of the declarations of a package keyed by name. The returned
+// map must not be modified.
+func (ps *PackageSet) Declarations(importPath string) map[string]*ParsedDeclaration {
+	return ps.declarations[importPath]
+}
+
+// EnumValues returns the constants declared with the given type in the package of the
+// declaration, in declaration order, like the values of an enum. References must be resolved.
+func (ps *PackageSet) EnumValues(pd *ParsedDeclaration) []*ParsedConstant {
+	values := make([]*ParsedConstant, 0)
+
+	pf := pd.File()
+	if pf == nil {
+		return values
+	}
+	for _, pf2c := range ps.Packages[pf.Module.FullName()] {
+		for pcIdx := range pf2c.Constants {
+			pc := &pf2c.Constants[pcIdx]
+			if pnnt, ok := pc.Type.(*ParsedNonNativeType); ok && pnnt.Ref == pd {
+				values = append(values, pc)
+			}
+		}
+	}
+	return values
+}
+
+// ResolveReferences works like the ResolveReferences function but reuses the package set index
+// to find the referenced declarations.
+func (ps *PackageSet) ResolveReferences() {
+	resolveReferences(ps)
+}
+
+// Query navigates the model following a dotted path, for example, models.User.Address.Street, and
+// returns the *ParsedDeclaration or *ParsedField found at the end. The first element is either a
+// package name, like `models`, or a full import path, like `github.com/acme/app/models`. Fields
+// are looked up through pointers, embedded fields and resolved references, so references must be
+// resolved before calling this method.
+func (ps *PackageSet) Query(path string) (interface{}, error) {
+	importPath, rest, err := ps.splitQuery(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := strings.Split(rest, ".")
+	pd := ps.Lookup(importPath, names[0])
+	if pd == nil {
+		return nil, fmt.Errorf("declaration %s not found in package %s", names[0], importPath)
+	}
+
+	var node interface{} = pd
+	t := pd.Type
+	for idx, name := range names[1:] {
+		field := findStructField(t, name)
+		if field == nil {
+			return nil, fmt.Errorf("field %s not found in %s", name, strings.Join(names[:idx+1], "."))
+		}
+		node = field
+		t = field.Type
+	}
+
+	// Done
+	return node, nil
+}
+
+func (ps *PackageSet) splitQuery(path string) (string, string, error) {
+	// A full import path contains slashes, the package is everything up to the first dot after the
+	// last slash
+	slashIdx := strings.LastIndex(path, "/")
+	dotIdx := strings.Index(path[slashIdx+1:], ".")
+	if dotIdx < 0 || dotIdx == len(path[slashIdx+1:])-1 {
+		return "", "", fmt.Errorf("invalid query %s", path)
+	}
+	dotIdx += slashIdx + 1
+	pkg := path[:dotIdx]
+	rest := path[dotIdx+1:]
+
+	if slashIdx >= 0 {
+		if _, ok := ps.Packages[pkg]; !ok {
+			return "", "", fmt.Errorf("package %s not found", pkg)
+		}
+		return pkg, rest, nil
+	}
+
+	// Find the package by name
+	importPath := ""
+	for ip, pfs := range ps.Packages {
+		if len(pfs) > 0 && pfs[0].Package == pkg {
+			if len(importPath) > 0 {
+				return "", "", fmt.Errorf("package name %s is ambiguous", pkg)
+			}
+			importPath = ip
+		}
+	}
+	if len(importPath) == 0 {
+		return "", "", fmt.Errorf("package %s not found", pkg)
+	}
+	return importPath, rest, nil
+}
+
+// -----------------------------------------------------------------------------
+
+// findStructField returns the field with the given name, declared or promoted, of the struct the
+// type refers to. Pointers and references are followed.
+func findStructField(t ParsedType, name string) *ParsedField {
+	// Follow pointers, including named ones like `type P *S`
+	visited := make(map[ParsedType]struct{})
+	for {
+		t = Underlying(t)
+		pp, ok := t.(*ParsedPointer)
+		if !ok {
+			break
+		}
+		if _, ok = visited[pp]; ok {
+			return nil
+		}
+		visited[pp] = struct{}{}
+		t = pp.ToType
+	}
+
+	ps, ok := t.(*ParsedStruct)
+	if !ok {
+		return nil
+	}
+	for _, ff := range ps.FlattenedFields() {
+		if ff.Name == name {
+			return ff.Field
+		}
+	}
+	return nil
+}
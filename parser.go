@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/parser"
 	"go/token"
 	"strconv"
@@ -18,30 +19,46 @@ type ParsedFile struct {
 	Package      string
 	Imports      []ParsedImport
 	Declarations []ParsedDeclaration
+	Constants    []ParsedConstant
+	Methods      []ParsedMethod
 
-	fileContent string
+	fileContent     string
+	typeParamScopes []map[string]*ParsedField
 }
 
 type ParsedDeclaration struct {
-	Name string
-	Type interface{}
-	Tags ParsedTags
+	Name       string
+	TypeParams []ParsedDeclarationTypeParam
+	Type       ParsedType
+	Tags       ParsedTags
+	IsAlias    bool            // True for `type A = B` declarations
+	Methods    []*ParsedMethod // Set by ResolveReferences
+	Doc        string          // Doc comment without comment markers
+
+	file       *ParsedFile
+	instanceOf *ParsedIndex // Set by Instantiate
 }
 
+type ParsedDeclarationTypeParam = ParsedField
+
 type ParsedNativeType struct {
 	Name string
 }
 
 type ParsedNonNativeType struct {
-	Name string
-	Ref  *ParsedDeclaration
+	Name       string
+	ImportPath string // Import path of the package for qualified names, if found in the file imports
+	Ref        *ParsedDeclaration
 }
 
 type ParsedField struct {
 	Names        []string
 	ImplicitName string
-	Type         interface{}
+	Type         ParsedType
 	Tags         ParsedTags
+	Doc          string // Doc or line comment without comment markers
+
+	rawTag string
 }
 
 type ParsedStruct struct {
@@ -58,40 +75,79 @@ type ParsedInterface struct {
 type ParsedInterfaceMethod = ParsedField
 
 type ParsedMap struct {
-	KeyType   interface{}
-	ValueType interface{}
+	KeyType   ParsedType
+	ValueType ParsedType
 }
 
 type ParsedArray struct {
 	Size      string // Empty string means a slice, ellipsis is an array of items known at compile time, else a constant or a type
 	ParsedInt *int64
-	ValueType interface{}
+	SizeError error // Set by ResolveReferences when the size expression cannot be evaluated
+	ValueType ParsedType
+
+	sizeExpr ast.Expr
 }
 
 type ParsedPointer struct {
-	ToType interface{}
+	ToType ParsedType
 }
 
 type ParsedChannel struct {
 	Dir  ast.ChanDir
-	Type interface{}
+	Type ParsedType
 }
 
 type ParsedIndex struct {
-	Type    interface{}
-	Indexes []interface{}
+	Type    ParsedType
+	Indexes []ParsedType
 }
 
 type ParsedFunction struct {
 	TypeParams []ParsedFunctionTypeParam
 	Params     []ParsedFunctionParam
 	Results    []ParsedFunctionResult
+	Variadic   bool // True if the last parameter is declared as `...T`, its type is stored as a slice
 }
 
 type ParsedFunctionTypeParam = ParsedField
 type ParsedFunctionParam = ParsedField
 type ParsedFunctionResult = ParsedField
 
+// ParsedTypeParamRef is an identifier bound by a type parameter list of a generic declaration or
+// function. Param points to the type parameter definition, whose Type is the constraint.
+type ParsedTypeParamRef struct {
+	Name  string
+	Param *ParsedField
+}
+
+// ParsedUnion represents a type constraint like `~int | ~string`
+type ParsedUnion struct {
+	Terms []ParsedUnionTerm
+}
+
+type ParsedUnionTerm struct {
+	Tilde bool
+	Type  ParsedType
+}
+
+type ParsedConstant struct {
+	Name        string
+	Type        ParsedType // Nil for untyped constants
+	Value       string     // Source text of the value expression
+	Iota        int
+	ParsedValue constant.Value // Set by ResolveReferences if the value can be evaluated
+
+	valueExpr ast.Expr
+}
+
+type ParsedMethod struct {
+	Name               string
+	Receiver           string // Name of the receiver's base type
+	PointerReceiver    bool
+	ReceiverTypeParams []ParsedField // Constraints are set by ResolveReferences
+	Type               *ParsedFunction
+}
+
 type ParsedImport struct {
 	Name         string
 	ImplicitName string
@@ -114,6 +170,8 @@ func ParseText(opts ParseTextOptions) (*ParsedFile, error) {
 		Filename:     opts.Filename,
 		Module:       opts.Module,
 		Declarations: make([]ParsedDeclaration, 0),
+		Constants:    make([]ParsedConstant, 0),
+		Methods:      make([]ParsedMethod, 0),
 		fileContent:  opts.Content,
 	}
 
@@ -154,19 +212,42 @@ func ParseText(opts ParseTextOptions) (*ParsedFile, error) {
 		pf.Imports = append(pf.Imports, pi)
 	}
 
-	// Parse type declarations
+	// Parse type and constant declarations, and methods
 	for _, decl := range fileAst.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			if funcDecl.Recv != nil && len(funcDecl.Recv.List) == 1 {
+				err = pf.parseMethod(funcDecl)
+				if err != nil {
+					return nil, fmt.Errorf("unable to parse method %s [err=%v]", funcDecl.Name.Name, err)
+				}
+			}
+			continue
+		}
+
 		if genDecl, ok := decl.(*ast.GenDecl); ok {
+			if genDecl.Tok == token.CONST {
+				err = pf.parseConstants(genDecl)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
 			for _, spec := range genDecl.Specs {
 				if typeSpec, ok2 := spec.(*ast.TypeSpec); ok2 {
-					var decl interface{}
+					var decl ParsedType
+					var typeParams []ParsedField
 
 					if len(typeSpec.Name.String()) == 0 {
 						continue
 					}
 					//  || (!typeSpec.Name.IsExported())
 
-					decl, err = pf.convertType(typeSpec.Type)
+					typeParams, err = pf.parseTypeParams(typeSpec.TypeParams)
+					if err == nil {
+						decl, err = pf.convertType(typeSpec.Type)
+					}
+					pf.popTypeParams()
 					if err != nil {
 						return nil, fmt.Errorf("unable to parse declaration %s [err=%v]", typeSpec.Name.String(), err)
 					}
@@ -175,11 +256,19 @@ func ParseText(opts ParseTextOptions) (*ParsedFile, error) {
 					}
 
 					pd := ParsedDeclaration{
-						Name: typeSpec.Name.String(),
-						Type: decl,
-						Tags: make(ParsedTags),
+						Name:       typeSpec.Name.String(),
+						TypeParams: typeParams,
+						Type:       decl,
+						Tags:       make(ParsedTags),
+						IsAlias:    typeSpec.Assign.IsValid(),
+						file:       &pf,
 					}
 
+					if typeSpec.Doc != nil {
+						pd.Doc = strings.TrimSpace(typeSpec.Doc.Text())
+					} else if genDecl.Doc != nil && !genDecl.Lparen.IsValid() {
+						pd.Doc = strings.TrimSpace(genDecl.Doc.Text())
+					}
 					if genDecl.Doc != nil {
 						pd.parseDirectives(genDecl.Doc)
 					}
@@ -197,7 +286,121 @@ func ParseText(opts ParseTextOptions) (*ParsedFile, error) {
 	return &pf, nil
 }
 
-func (pf *ParsedFile) convertType(expr ast.Expr) (interface{}, error) {
+func (pf *ParsedFile) parseConstants(genDecl *ast.GenDecl) error {
+	var lastTypeExpr ast.Expr
+	var lastValues []ast.Expr
+	var err error
+
+	for specIdx, spec := range genDecl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		// Inside a group, a spec without type and values repeats the previous ones
+		typeExpr := valueSpec.Type
+		values := valueSpec.Values
+		if typeExpr == nil && len(values) == 0 {
+			typeExpr = lastTypeExpr
+			values = lastValues
+		} else {
+			lastTypeExpr = typeExpr
+			lastValues = values
+		}
+
+		for nameIdx, name := range valueSpec.Names {
+			if name.Name == "_" {
+				continue
+			}
+
+			pc := ParsedConstant{
+				Name: name.Name,
+				Iota: specIdx,
+			}
+
+			if typeExpr != nil {
+				pc.Type, err = pf.convertType(typeExpr)
+				if err != nil {
+					return fmt.Errorf("unable to parse constant %s [err=%v]", name.Name, err)
+				}
+			}
+
+			if nameIdx < len(values) {
+				pc.valueExpr = values[nameIdx]
+				pc.Value = pf.sourceText(values[nameIdx])
+			}
+
+			pf.Constants = append(pf.Constants, pc)
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func (pf *ParsedFile) parseMethod(funcDecl *ast.FuncDecl) error {
+	var err error
+
+	pm := ParsedMethod{
+		Name:               funcDecl.Name.Name,
+		ReceiverTypeParams: make([]ParsedField, 0),
+	}
+
+	// Parse receiver, for example: (r *Box[T])
+	recvType := funcDecl.Recv.List[0].Type
+	for {
+		parenExpr, ok := recvType.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		recvType = parenExpr.X
+	}
+	if starExpr, ok := recvType.(*ast.StarExpr); ok {
+		pm.PointerReceiver = true
+		recvType = starExpr.X
+	}
+
+	typeParamExprs := make([]ast.Expr, 0)
+	switch rt := recvType.(type) {
+	case *ast.IndexExpr:
+		recvType = rt.X
+		typeParamExprs = append(typeParamExprs, rt.Index)
+	case *ast.IndexListExpr:
+		recvType = rt.X
+		typeParamExprs = append(typeParamExprs, rt.Indices...)
+	}
+
+	recvIdent, ok := recvType.(*ast.Ident)
+	if !ok {
+		return errors.New("unsupported receiver type")
+	}
+	pm.Receiver = recvIdent.Name
+
+	// The receiver type parameters are in scope for the whole method signature
+	scope := make(map[string]*ParsedField)
+	pm.ReceiverTypeParams = make([]ParsedField, len(typeParamExprs))
+	for idx, typeParamExpr := range typeParamExprs {
+		pm.ReceiverTypeParams[idx].Names = make([]string, 0)
+		if typeParamIdent, ok2 := typeParamExpr.(*ast.Ident); ok2 {
+			pm.ReceiverTypeParams[idx].Names = append(pm.ReceiverTypeParams[idx].Names, typeParamIdent.Name)
+			scope[typeParamIdent.Name] = &pm.ReceiverTypeParams[idx]
+		}
+	}
+	pf.typeParamScopes = append(pf.typeParamScopes, scope)
+	defer pf.popTypeParams()
+
+	pm.Type, err = pf.parseFunction(funcDecl.Type)
+	if err != nil {
+		return err
+	}
+
+	pf.Methods = append(pf.Methods, pm)
+
+	// Done
+	return nil
+}
+
+func (pf *ParsedFile) convertType(expr ast.Expr) (ParsedType, error) {
 	var node interface{} = expr
 
 	switch node := node.(type) {
@@ -217,7 +420,11 @@ func (pf *ParsedFile) convertType(expr ast.Expr) (interface{}, error) {
 		return pf.parseMap(node)
 
 	case *ast.ArrayType:
-		return pf.parseArray(node)
+		pa, err := pf.parseArray(node)
+		if pa == nil {
+			return nil, err // Avoid returning a nil pointer wrapped in a non-nil interface
+		}
+		return pa, nil
 
 	case *ast.StarExpr:
 		return pf.parsePointer(node)
@@ -229,25 +436,62 @@ func (pf *ParsedFile) convertType(expr ast.Expr) (interface{}, error) {
 
 	case *ast.SelectorExpr:
 		if xIdent, ok2 := node.X.(*ast.Ident); ok2 {
-			return &ParsedNonNativeType{
+			pnnt := ParsedNonNativeType{
 				Name: xIdent.Name + "." + node.Sel.Name,
-			}, nil
+			}
+			for _, pi := range pf.Imports {
+				if pi.PackageName() == xIdent.Name {
+					pnnt.ImportPath = pi.Path
+					break
+				}
+			}
+			return &pnnt, nil
+		}
+
+	case *ast.Ellipsis:
+		// Only valid for the last parameter of a function, see parseFunction
+		valueType, err := pf.convertType(node.Elt)
+		if valueType == nil {
+			return nil, err
 		}
+		return &ParsedArray{
+			ValueType: valueType,
+		}, nil
 
 	case *ast.ChanType:
 		return pf.parseChannel(node)
 
 	case *ast.FuncType:
 		return pf.parseFunction(node)
+
+	case *ast.BinaryExpr:
+		if node.Op == token.OR {
+			return pf.parseUnion(node)
+		}
+
+	case *ast.UnaryExpr:
+		if node.Op == token.TILDE {
+			return pf.parseUnion(node)
+		}
 	}
 
 	// Not supported
 	return nil, nil
 }
 
-func (pf *ParsedFile) parseIdent(expr ast.Expr) (interface{}, error) {
+func (pf *ParsedFile) parseIdent(expr ast.Expr) (ParsedType, error) {
 	ident := expr.(*ast.Ident)
 
+	// Identifiers bound by an enclosing type parameter list take precedence
+	for idx := len(pf.typeParamScopes) - 1; idx >= 0; idx-- {
+		if param, ok := pf.typeParamScopes[idx][ident.Name]; ok {
+			return &ParsedTypeParamRef{
+				Name:  ident.Name,
+				Param: param,
+			}, nil
+		}
+	}
+
 	if IsNativeType(ident.Name) {
 		return &ParsedNativeType{
 			Name: ident.Name,
@@ -340,6 +584,9 @@ func (pf *ParsedFile) parseArray(expr ast.Expr) (*ParsedArray, error) {
 		return &pa, nil
 	}
 
+	// Keep the expression, so it can be evaluated once constants are resolved
+	pa.sizeExpr = a.Len
+
 	if lenSelExpr, ok := a.Len.(*ast.SelectorExpr); ok {
 		if xIdent, ok2 := lenSelExpr.X.(*ast.Ident); ok2 {
 			pa.Size = xIdent.Name + "." + lenSelExpr.Sel.Name
@@ -378,7 +625,7 @@ func (pf *ParsedFile) parseArray(expr ast.Expr) (*ParsedArray, error) {
 		return &pa, nil
 	}
 
-	pa.Size = pf.fileContent[a.Len.Pos()-1 : a.Len.End()-1]
+	pa.Size = pf.sourceText(a.Len)
 
 	// Done
 	return &pa, nil
@@ -404,13 +651,13 @@ func (pf *ParsedFile) parsePointer(expr ast.Expr) (*ParsedPointer, error) {
 }
 
 func (pf *ParsedFile) parseIndex(expr ast.Expr) (*ParsedIndex, error) {
-	var idx interface{}
+	var idx ParsedType
 	var err error
 
 	ie := expr.(*ast.IndexExpr)
 
 	pi := ParsedIndex{
-		Indexes: make([]interface{}, 0),
+		Indexes: make([]ParsedType, 0),
 	}
 
 	pi.Type, err = pf.convertType(ie.X)
@@ -429,13 +676,13 @@ func (pf *ParsedFile) parseIndex(expr ast.Expr) (*ParsedIndex, error) {
 }
 
 func (pf *ParsedFile) parseIndexList(expr ast.Expr) (*ParsedIndex, error) {
-	var idx interface{}
+	var idx ParsedType
 	var err error
 
 	ile := expr.(*ast.IndexListExpr)
 
 	pi := ParsedIndex{
-		Indexes: make([]interface{}, 0),
+		Indexes: make([]ParsedType, 0),
 	}
 
 	pi.Type, err = pf.convertType(ile.X)
@@ -483,14 +730,19 @@ func (pf *ParsedFile) parseFunction(expr ast.Expr) (*ParsedFunction, error) {
 
 	pfunc := ParsedFunction{}
 
-	pfunc.TypeParams, err = pf.parseFields(ft.TypeParams)
+	pfunc.TypeParams, err = pf.parseTypeParams(ft.TypeParams)
+	defer pf.popTypeParams()
 	if err != nil {
 		return nil, err
 	}
+
 	pfunc.Params, err = pf.parseFields(ft.Params)
 	if err != nil {
 		return nil, err
 	}
+	if ft.Params != nil && len(ft.Params.List) > 0 {
+		_, pfunc.Variadic = ft.Params.List[len(ft.Params.List)-1].Type.(*ast.Ellipsis)
+	}
 	pfunc.Results, err = pf.parseFields(ft.Results)
 	if err != nil {
 		return nil, err
@@ -500,6 +752,88 @@ func (pf *ParsedFile) parseFunction(expr ast.Expr) (*ParsedFunction, error) {
 	return &pfunc, nil
 }
 
+// parseTypeParams parses a type parameter list and opens a new scope with its identifiers. The
+// scope must be closed with popTypeParams even if an error is returned.
+func (pf *ParsedFile) parseTypeParams(fields *ast.FieldList) ([]ParsedField, error) {
+	var err error
+
+	scope := make(map[string]*ParsedField)
+	pf.typeParamScopes = append(pf.typeParamScopes, scope)
+
+	if fields == nil || fields.List == nil {
+		return make([]ParsedField, 0), nil
+	}
+
+	// Allocate the parameters first because constraints may refer to any of them
+	typeParams := make([]ParsedField, len(fields.List))
+	for idx, field := range fields.List {
+		typeParams[idx].Names = make([]string, 0)
+		for _, name := range field.Names {
+			typeParams[idx].Names = append(typeParams[idx].Names, name.Name)
+			scope[name.Name] = &typeParams[idx]
+		}
+	}
+
+	for idx, field := range fields.List {
+		typeParams[idx].Type, err = pf.convertType(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if typeParams[idx].Type == nil {
+			return nil, errors.New("unsupported type parameter constraint")
+		}
+	}
+
+	// Done
+	return typeParams, nil
+}
+
+func (pf *ParsedFile) popTypeParams() {
+	pf.typeParamScopes = pf.typeParamScopes[:len(pf.typeParamScopes)-1]
+}
+
+func (pf *ParsedFile) parseUnion(expr ast.Expr) (*ParsedUnion, error) {
+	var err error
+
+	pu := ParsedUnion{
+		Terms: make([]ParsedUnionTerm, 0),
+	}
+
+	// Unions are left-associative, so walk the left side while collecting terms
+	terms := make([]ast.Expr, 0)
+	for {
+		binExpr, ok := expr.(*ast.BinaryExpr)
+		if !ok || binExpr.Op != token.OR {
+			break
+		}
+		terms = append([]ast.Expr{binExpr.Y}, terms...)
+		expr = binExpr.X
+	}
+	terms = append([]ast.Expr{expr}, terms...)
+
+	for _, term := range terms {
+		put := ParsedUnionTerm{}
+
+		if unaryExpr, ok := term.(*ast.UnaryExpr); ok && unaryExpr.Op == token.TILDE {
+			put.Tilde = true
+			term = unaryExpr.X
+		}
+
+		put.Type, err = pf.convertType(term)
+		if err != nil {
+			return nil, err
+		}
+		if put.Type == nil {
+			return nil, errors.New("unsupported union term")
+		}
+
+		pu.Terms = append(pu.Terms, put)
+	}
+
+	// Done
+	return &pu, nil
+}
+
 func (pf *ParsedFile) parseFields(fields *ast.FieldList) ([]ParsedField, error) {
 	var err error
 
@@ -516,7 +850,18 @@ func (pf *ParsedFile) parseFields(fields *ast.FieldList) ([]ParsedField, error)
 			}
 
 			if field.Tag != nil {
-				pfld.Tags = scanTags(field.Tag.Value[1 : len(field.Tag.Value)-1]) // remove side `
+				tag, err2 := strconv.Unquote(field.Tag.Value)
+				if err2 != nil {
+					tag = field.Tag.Value[1 : len(field.Tag.Value)-1] // remove side `
+				}
+				pfld.Tags = scanTags(tag)
+				pfld.rawTag = tag
+			}
+
+			if field.Doc != nil {
+				pfld.Doc = strings.TrimSpace(field.Doc.Text())
+			} else if field.Comment != nil {
+				pfld.Doc = strings.TrimSpace(field.Comment.Text())
 			}
 
 			pfld.Type, err = pf.convertType(field.Type)
@@ -538,6 +883,10 @@ func (pf *ParsedFile) parseFields(fields *ast.FieldList) ([]ParsedField, error)
 	return fieldsList, err
 }
 
+func (pf *ParsedFile) sourceText(node ast.Node) string {
+	return pf.fileContent[node.Pos()-1 : node.End()-1]
+}
+
 func (pd *ParsedDeclaration) parseDirectives(commentGroup *ast.CommentGroup) {
 	for _, line := range strings.Split(commentGroup.Text(), "\n") {
 		line := strings.TrimSpace(line)
@@ -554,6 +903,24 @@ func (pi *ParsedImport) PackageName() string {
 	return pi.ImplicitName
 }
 
+func (ptpr *ParsedTypeParamRef) Constraint() ParsedType {
+	if ptpr.Param == nil {
+		return nil
+	}
+	return ptpr.Param.Type
+}
+
+// RawTag returns the original tag string of a struct field without quotes
+func (pf *ParsedField) RawTag() string {
+	return pf.rawTag
+}
+
+// File returns the parsed file where the declaration is located. It returns nil for declarations
+// not created by the parser.
+func (pd *ParsedDeclaration) File() *ParsedFile {
+	return pd.file
+}
+
 func (pf *ParsedField) Identifiers() []string {
 	if len(pf.ImplicitName) > 0 {
 		return []string{pf.ImplicitName}
@@ -563,7 +930,7 @@ func (pf *ParsedField) Identifiers() []string {
 
 // -----------------------------------------------------------------------------
 
-func guessImplicitName(t interface{}) string {
+func guessImplicitName(t ParsedType) string {
 	switch tType := t.(type) {
 	case *ParsedNativeType:
 		return tType.Name
@@ -572,6 +939,12 @@ func guessImplicitName(t interface{}) string {
 		_, name := GetIdentifierParts(tType.Name)
 		return name
 
+	case *ParsedTypeParamRef:
+		return tType.Name
+
+	case *ParsedIndex:
+		return guessImplicitName(tType.Type)
+
 	case *ParsedArray:
 		return guessImplicitName(tType.ValueType)
 
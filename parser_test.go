@@ -1,6 +1,9 @@
 package parser_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	parser "github.com/mxmauro/gofile-parser"
@@ -74,4 +77,1404 @@ func main() {
 	if pfs[0].Declarations[2].Type.(*parser.ParsedStruct).Fields[2].Type.(*parser.ParsedArray).ParsedInt == nil {
 		t.Fatalf("unable to indentify array size")
 	}
+
+	if pfs[0].Declarations[2].Type.Kind() != parser.StructKind ||
+		pfs[0].Declarations[2].Type.(*parser.ParsedStruct).Fields[1].Type.Kind() != parser.SliceKind ||
+		pfs[0].Declarations[2].Type.(*parser.ParsedStruct).Fields[2].Type.Kind() != parser.ArrayKind {
+		t.Fatalf("wrong kind")
+	}
+}
+
+func TestImportNames(t *testing.T) {
+	pfs := make([]*parser.ParsedFile, 0)
+
+	for _, opts := range []parser.ParseTextOptions{
+		{
+			Content: `
+package common
+
+const Scale = 100
+
+type Money struct {
+	Amount int64
+}
+`,
+			Filename: "common.go",
+			Module: parser.Module{
+				Name:   "github.com/mxmauro/gofile-parser-test",
+				SubDir: "common",
+			},
+		},
+		{
+			Content: `
+package main
+
+import (
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+type A struct {
+	B common.Money
+	C [common.Scale]int
+}
+`,
+			Filename: "a.go",
+			Module: parser.Module{
+				Name:   "github.com/mxmauro/gofile-parser-test",
+				SubDir: "",
+			},
+		},
+		{
+			Content: `
+package main
+
+import (
+	money "github.com/mxmauro/gofile-parser-test/common"
+)
+
+type B struct {
+	B money.Money
+	C [money.Scale]int
+	D common.Money
+}
+`,
+			Filename: "b.go",
+			Module: parser.Module{
+				Name:   "github.com/mxmauro/gofile-parser-test",
+				SubDir: "",
+			},
+		},
+	} {
+		pf, err := parser.ParseText(opts)
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		pfs = append(pfs, pf)
+	}
+
+	parser.ResolveReferences(pfs)
+
+	// Imports without an alias are referenced by the last element of their path
+	for _, pf := range pfs[1:] {
+		fields := pf.Declarations[0].Type.(*parser.ParsedStruct).Fields
+		if fields[0].Type.(*parser.ParsedNonNativeType).Ref != &pfs[0].Declarations[0] {
+			t.Fatalf("wrong reference for %s", fields[0].Type.(*parser.ParsedNonNativeType).Name)
+		}
+		if pa := fields[1].Type.(*parser.ParsedArray); pa.ParsedInt == nil || *pa.ParsedInt != 100 {
+			t.Fatalf("wrong array size for %s [err=%v]", pa.Size, pa.SizeError)
+		}
+	}
+
+	// An alias hides the implicit name
+	if pfs[2].Declarations[0].Type.(*parser.ParsedStruct).Fields[2].Type.(*parser.ParsedNonNativeType).Ref != nil {
+		t.Fatalf("unexpected reference through the implicit name of an aliased import")
+	}
+}
+
+func TestArraySizes(t *testing.T) {
+	pfs := make([]*parser.ParsedFile, 0)
+
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package sizes
+
+const (
+	KB = 1 << (10 * (iota + 1))
+	MB
+)
+
+const Name = "abcdef"
+`,
+		Filename: "sizes.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "sizes",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	pfs = append(pfs, pf)
+
+	pf, err = parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"github.com/mxmauro/gofile-parser-test/sizes"
+)
+
+const N = 4
+
+type A struct {
+	B [N]byte
+	C [sizes.KB]byte
+	D [2*N + 1]int
+	E [len(sizes.Name)]int
+	F [Unknown]int
+	G [sizes.MB / sizes.KB]int
+	H [uint8(255)]int
+	I [uint8(-1)]int
+	J [int8(300)]int
+	K [uint64(1 << 64)]int
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	pfs = append(pfs, pf)
+
+	parser.ResolveReferences(pfs)
+
+	expected := []int64{4, 1024, 9, 6, -1, 1024, 255, -1, -1, -1}
+	for idx, field := range pfs[1].Declarations[0].Type.(*parser.ParsedStruct).Fields {
+		pa := field.Type.(*parser.ParsedArray)
+		if expected[idx] < 0 {
+			if pa.ParsedInt != nil || pa.SizeError == nil {
+				t.Fatalf("array size %s should not be evaluated", pa.Size)
+			}
+		} else if pa.ParsedInt == nil || *pa.ParsedInt != expected[idx] {
+			t.Fatalf("wrong array size for %s [err=%v]", pa.Size, pa.SizeError)
+		}
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type User struct {
+	Name string
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Page[T any] struct {
+	Items []T
+	Next  *Page[T]
+	First Pair[string, T]
+}
+
+type UserPage struct {
+	Page Page[User]
+}
+
+type Broken[T any] struct {
+	Pair Pair[T]
+}
+
+type BrokenPage struct {
+	Page Broken[User]
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	pi := pf.Declarations[3].Type.(*parser.ParsedStruct).Fields[0].Type.(*parser.ParsedIndex)
+	pd, err := parser.Instantiate(pi)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := pd.Type.(*parser.ParsedStruct)
+	if ps.Fields[0].Type.(*parser.ParsedArray).ValueType.(*parser.ParsedNonNativeType).Ref != &pf.Declarations[0] {
+		t.Fatalf("type parameter not replaced")
+	}
+	if ps.Fields[1].Type.(*parser.ParsedPointer).ToType.(*parser.ParsedNonNativeType).Ref != pd {
+		t.Fatalf("recursive instantiation not reused")
+	}
+	pair := ps.Fields[2].Type.(*parser.ParsedNonNativeType).Ref.Type.(*parser.ParsedStruct)
+	if pair.Fields[1].Type.(*parser.ParsedNonNativeType).Ref != &pf.Declarations[0] {
+		t.Fatalf("nested generic not instantiated")
+	}
+
+	// The generic declaration must remain untouched
+	if _, ok := pf.Declarations[2].Type.(*parser.ParsedStruct).Fields[0].Type.(*parser.ParsedArray).ValueType.(*parser.ParsedTypeParamRef); !ok {
+		t.Fatalf("generic declaration modified")
+	}
+
+	// Errors of nested instantiations must be reported
+	pi = pf.Declarations[5].Type.(*parser.ParsedStruct).Fields[0].Type.(*parser.ParsedIndex)
+	_, err = parser.Instantiate(pi)
+	if err == nil {
+		t.Fatalf("invalid nested instantiation not detected")
+	}
+}
+
+func TestTypeParamScope(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type T struct{}
+
+type Box[T any, S ~[]T | ~string] struct {
+	V T
+	W S
+	X func(T) T
+}
+
+type Other struct {
+	V T
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	box := &pf.Declarations[1]
+	fields := box.Type.(*parser.ParsedStruct).Fields
+	if ref, ok := fields[0].Type.(*parser.ParsedTypeParamRef); !ok || ref.Param != &box.TypeParams[0] {
+		t.Fatalf("type parameter not bound")
+	}
+	if ref, ok := fields[1].Type.(*parser.ParsedTypeParamRef); !ok || len(ref.Constraint().(*parser.ParsedUnion).Terms) != 2 {
+		t.Fatalf("wrong type parameter constraint")
+	}
+	if _, ok := fields[2].Type.(*parser.ParsedFunction).Params[0].Type.(*parser.ParsedTypeParamRef); !ok {
+		t.Fatalf("type parameter not bound inside function")
+	}
+
+	if pf.Declarations[2].Type.(*parser.ParsedStruct).Fields[0].Type.(*parser.ParsedNonNativeType).Ref != &pf.Declarations[0] {
+		t.Fatalf("wrong reference")
+	}
+}
+
+func TestRecursiveDeclarations(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Node struct {
+	Next *Node
+}
+
+type A struct {
+	B []B
+}
+
+type B struct {
+	A map[string]*A
+}
+
+type C struct {
+	A A
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	groups := parser.FindRecursiveDeclarations([]*parser.ParsedFile{pf})
+	if len(groups) != 3 || len(groups[&pf.Declarations[0]]) != 1 || len(groups[&pf.Declarations[1]]) != 2 {
+		t.Fatalf("wrong recursive declarations")
+	}
+	if _, ok := groups[&pf.Declarations[3]]; ok || parser.IsRecursive(&pf.Declarations[3]) {
+		t.Fatalf("declaration C must not be recursive")
+	}
+	if !parser.IsRecursive(&pf.Declarations[2]) {
+		t.Fatalf("declaration B must be recursive")
+	}
+
+	count := 0
+	parser.WalkDeclarations(&pf.Declarations[3], func(_ *parser.ParsedDeclaration) bool {
+		count += 1
+		return true
+	})
+	if count != 3 {
+		t.Fatalf("wrong number of visited declarations")
+	}
+}
+
+func TestUnderlying(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type ID UserID
+
+type UserID string
+
+type Count = Total
+
+type Total uint16
+
+type User struct {
+	ID    ID
+	Count Count
+}
+
+type Loop1 Loop2
+
+type Loop2 Loop1
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	fields := pf.Declarations[4].Type.(*parser.ParsedStruct).Fields
+	if !parser.IsString(fields[0].Type) || parser.IsNumeric(fields[0].Type) {
+		t.Fatalf("wrong underlying type for ID")
+	}
+	if !parser.IsNumeric(fields[1].Type) || !parser.IsUnsigned(fields[1].Type) {
+		t.Fatalf("wrong underlying type for Count")
+	}
+	if fields[1].Type.(*parser.ParsedNonNativeType).Unalias().(*parser.ParsedNonNativeType).Ref != &pf.Declarations[3] {
+		t.Fatalf("wrong unaliased type for Count")
+	}
+	if !parser.IsStruct(&parser.ParsedNonNativeType{Name: "User", Ref: &pf.Declarations[4]}) {
+		t.Fatalf("User must be a struct")
+	}
+	if pf.Declarations[5].Underlying() != nil {
+		t.Fatalf("cycle not detected")
+	}
+}
+
+func TestRuneIsNative(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Letter rune
+
+type Word struct {
+	Letters []rune
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	pnt, ok := pf.Declarations[0].Type.(*parser.ParsedNativeType)
+	if !ok || pnt.Name != "rune" {
+		t.Fatalf("rune must be a native type")
+	}
+	if !parser.IsInteger(&parser.ParsedNonNativeType{Name: "Letter", Ref: &pf.Declarations[0]}) {
+		t.Fatalf("Letter must be an integer")
+	}
+	pa := pf.Declarations[1].Type.(*parser.ParsedStruct).Fields[0].Type.(*parser.ParsedArray)
+	if _, ok = pa.ValueType.(*parser.ParsedNativeType); !ok {
+		t.Fatalf("rune must be a native type")
+	}
+}
+
+func TestAsParsedType(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type User struct {
+	Tags map[string][]int
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	// Nodes kept in interface{} variables convert back to the same node
+	var v interface{} = pf.Declarations[0].Type.(*parser.ParsedStruct).Fields[0].Type
+	pt := parser.AsParsedType(v)
+	if pt == nil || pt.Kind() != parser.MapKind || pt.(*parser.ParsedMap) != v.(*parser.ParsedMap) {
+		t.Fatalf("wrong conversion of %v", v)
+	}
+
+	for _, v = range []interface{}{nil, "string", parser.ParsedMap{}} {
+		if pt = parser.AsParsedType(v); pt != nil {
+			t.Fatalf("%v must not be converted", v)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	pfs := make([]*parser.ParsedFile, 0)
+
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package models
+
+type User struct {
+	Name string
+}
+`,
+		Filename: "models.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "models",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	pfs = append(pfs, pf)
+
+	pf, err = parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	m "github.com/mxmauro/gofile-parser-test/models"
+	"time"
+)
+
+const N = 4
+
+type Page[T any] struct {
+	Items []T
+}
+
+type A struct {
+	B map[string][]*m.User
+	C chan (<-chan int)
+	D func(format string, args ...interface{}) (n int, err error)
+	E struct {
+		X int ` + "`json:\"x\"`" + `
+	}
+	F Page[m.User]
+	G [N]time.Time
+	H func() error
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	pfs = append(pfs, pf)
+
+	parser.ResolveReferences(pfs)
+
+	expected := []string{
+		"map[string][]*m.User",
+		"chan (<-chan int)",
+		"func(format string, args ...interface{}) (n int, err error)",
+		"struct{X int `json:\"x\"`}",
+		"Page[m.User]",
+		"[N]time.Time",
+		"func() error",
+	}
+	for idx, field := range pfs[1].Declarations[1].Type.(*parser.ParsedStruct).Fields {
+		if s := parser.Render(field.Type, nil); s != expected[idx] {
+			t.Fatalf("wrong rendered type %s, expected %s", s, expected[idx])
+		}
+	}
+
+	// Render relative to the models package
+	qualifier := func(importPath string) string {
+		switch importPath {
+		case "github.com/mxmauro/gofile-parser-test/models":
+			return ""
+		case "github.com/mxmauro/gofile-parser-test":
+			return "app"
+		}
+		return importPath
+	}
+	fields := pfs[1].Declarations[1].Type.(*parser.ParsedStruct).Fields
+	if s := parser.Render(fields[0].Type, qualifier); s != "map[string][]*User" {
+		t.Fatalf("wrong qualified type %s", s)
+	}
+	if s := parser.Render(fields[4].Type, qualifier); s != "app.Page[User]" {
+		t.Fatalf("wrong qualified type %s", s)
+	}
+	if s := parser.Render(fields[5].Type, qualifier); s != "[4]time.Time" {
+		t.Fatalf("wrong qualified type %s", s)
+	}
+}
+
+type fieldCounter struct {
+	count int
+}
+
+func (fc *fieldCounter) Visit(node interface{}, _ parser.Path) parser.Visitor {
+	if _, ok := node.(*parser.ParsedField); ok {
+		fc.count += 1
+	}
+	return fc
+}
+
+func TestWalk(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type User struct {
+	Name    string
+	Address *Address
+	Parent  *User
+}
+
+type Address struct {
+	Street string
+	Geo    struct {
+		Lat, Lng float64
+	}
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	paths := make([]string, 0)
+	parser.WalkReferences(&pf.Declarations[0], func(node interface{}, path parser.Path) bool {
+		if _, ok := node.(*parser.ParsedField); ok {
+			paths = append(paths, path.String())
+		}
+		return true
+	})
+	expected := "User.Name User.Address User.Address.Street User.Address.Geo User.Address.Geo.Lat,Lng User.Parent"
+	if strings.Join(paths, " ") != expected {
+		t.Fatalf("wrong paths: %v", paths)
+	}
+
+	fc := fieldCounter{}
+	parser.WalkVisitor(&fc, pf)
+	if fc.count != 6 {
+		t.Fatalf("wrong number of visited fields")
+	}
+}
+
+func TestIdentical(t *testing.T) {
+	pfs := make([]*parser.ParsedFile, 0)
+
+	for _, pkg := range []string{"users", "accounts"} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content: `
+package ` + pkg + `
+
+type DTO struct {
+	ID, Name string ` + "`json:\"id\"`" + `
+	Next     *DTO
+	Callback func(a int, b string) error
+}
+
+type IDs []string
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type ReadCloser interface {
+	Reader
+	Close() error
+}
+
+type Explicit interface {
+	Close() error
+	Read(p []byte) (n int, err error)
+}
+
+type Loop1 Loop2
+
+type Loop2 Loop1
+`,
+			Filename: pkg + ".go",
+			Module: parser.Module{
+				Name:   "github.com/mxmauro/gofile-parser-test",
+				SubDir: pkg,
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		pfs = append(pfs, pf)
+	}
+
+	parser.ResolveReferences(pfs)
+
+	users := &parser.ParsedNonNativeType{Name: "DTO", Ref: &pfs[0].Declarations[0]}
+	accounts := &parser.ParsedNonNativeType{Name: "DTO", Ref: &pfs[1].Declarations[0]}
+	if parser.Identical(users, accounts) {
+		t.Fatalf("different declarations must not be identical")
+	}
+	if !parser.IdenticalWithOptions(users, accounts, parser.IdenticalOptions{IgnoreNames: true}) {
+		t.Fatalf("declarations must be structurally identical")
+	}
+	if !parser.Identical(users, &parser.ParsedNonNativeType{Name: "users.DTO", Ref: &pfs[0].Declarations[0]}) {
+		t.Fatalf("same declarations must be identical")
+	}
+
+	fn := &parser.ParsedFunction{
+		Params: []parser.ParsedField{
+			{Type: &parser.ParsedNativeType{Name: "int"}},
+			{Type: &parser.ParsedNativeType{Name: "string"}},
+		},
+		Results: []parser.ParsedField{
+			{Type: &parser.ParsedNonNativeType{Name: "error"}},
+		},
+	}
+	if !parser.Identical(fn, pfs[0].Declarations[0].Type.(*parser.ParsedStruct).Fields[2].Type) {
+		t.Fatalf("function signatures must be identical")
+	}
+
+	ids := &parser.ParsedNonNativeType{Name: "IDs", Ref: &pfs[0].Declarations[1]}
+	slice := &parser.ParsedArray{ValueType: &parser.ParsedNativeType{Name: "string"}}
+	if parser.Identical(ids, slice) || !parser.AssignableTo(slice, ids) {
+		t.Fatalf("a slice must be assignable but not identical to a named slice")
+	}
+	if parser.AssignableTo(ids, &parser.ParsedNonNativeType{Name: "IDs", Ref: &pfs[1].Declarations[1]}) {
+		t.Fatalf("different named types must not be assignable")
+	}
+
+	if !parser.Identical(pfs[0].Declarations[3].Type, pfs[0].Declarations[4].Type) {
+		t.Fatalf("embedded interfaces must be expanded")
+	}
+	loop1 := &parser.ParsedNonNativeType{Name: "Loop1", Ref: &pfs[0].Declarations[5]}
+	loop2 := &parser.ParsedNonNativeType{Name: "Loop2", Ref: &pfs[0].Declarations[6]}
+	if parser.IdenticalWithOptions(loop1, loop2, parser.IdenticalOptions{IgnoreNames: true}) {
+		t.Fatalf("invalid declarations must not be identical")
+	}
+}
+
+func TestClone(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Node[T any] struct {
+	Value T
+	Next  *Node[T]
+	Tags  map[string]Tag
+}
+
+type Tag string
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	clonedPf := parser.Clone(pf)
+
+	fields := clonedPf.Declarations[0].Type.(*parser.ParsedStruct).Fields
+	if fields[0].Type.(*parser.ParsedTypeParamRef).Param != &clonedPf.Declarations[0].TypeParams[0] {
+		t.Fatalf("type parameter not remapped")
+	}
+	if fields[1].Type.(*parser.ParsedPointer).ToType.(*parser.ParsedIndex).Type.(*parser.ParsedNonNativeType).Ref != &clonedPf.Declarations[0] {
+		t.Fatalf("reference not remapped")
+	}
+	if fields[2].Type.(*parser.ParsedMap).ValueType.(*parser.ParsedNonNativeType).Ref != &clonedPf.Declarations[1] {
+		t.Fatalf("reference not remapped")
+	}
+
+	// Modifying the copy must not affect the original
+	clonedPf.Declarations[0].Type.(*parser.ParsedStruct).Fields = fields[:1]
+	if len(pf.Declarations[0].Type.(*parser.ParsedStruct).Fields) != 3 {
+		t.Fatalf("original file modified")
+	}
+}
+
+func TestFlattenedFields(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Base struct {
+	ID      string
+	Created int64
+}
+
+type Audit struct {
+	Created int64
+	User    string
+}
+
+type Page[T any] struct {
+	Items []T
+}
+
+type Entity struct {
+	*Base
+	Audit
+	Page[string]
+	User int
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	paths := make([]string, 0)
+	for _, ff := range pf.Declarations[3].Type.(*parser.ParsedStruct).FlattenedFields() {
+		path := strings.Join(ff.Path, ".")
+		if ff.ViaPointer {
+			path = "*" + path
+		}
+		paths = append(paths, path)
+	}
+
+	// Created is ambiguous and User is shadowed
+	expected := "Base Audit Page User *Base.ID Page.Items"
+	if strings.Join(paths, " ") != expected {
+		t.Fatalf("wrong flattened fields: %v", paths)
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Base struct {
+	ID      string ` + "`json:\"id\"`" + `
+	Created int64  ` + "`json:\"created\"`" + `
+}
+
+type Audit struct {
+	Created int64 ` + "`json:\"created\"`" + `
+	User    string
+}
+
+type Entity struct {
+	*Base
+	Audit  ` + "`json:\"audit\"`" + `
+	Count  int    ` + "`json:\",string\"`" + `
+	Note   string ` + "`json:\"note,omitempty\"`" + `
+	Hidden string ` + "`json:\"-\"`" + `
+	secret string
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	names := make([]string, 0)
+	for _, jf := range pf.Declarations[2].Type.(*parser.ParsedStruct).JSONFields() {
+		name := jf.Name
+		if jf.OmitEmpty {
+			name += "?"
+		}
+		if jf.AsString {
+			name += "!"
+		}
+		names = append(names, name)
+	}
+
+	// Base is inlined and Audit is not because it has a json name
+	expected := "id created audit Count! note?"
+	if strings.Join(names, " ") != expected {
+		t.Fatalf("wrong json fields: %v", names)
+	}
+}
+
+func TestMethodSets(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"context"
+)
+
+type Named interface {
+	Name() string
+}
+
+type Store interface {
+	Named
+	Save(value int) error
+}
+
+type Base struct{}
+
+func (b Base) Name() string { return "" }
+
+type Entity struct {
+	Base
+}
+
+func (e *Entity) Save(value int) error { return nil }
+
+type Box[T any] struct{}
+
+func (b Box[T]) Get() T { var v T; return v }
+
+type Other struct{}
+
+func (o Other) Save(value string) error { return nil }
+
+type Runner interface {
+	Run(ctx context.Context) error
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	pf2, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	stdctx "context"
+)
+
+type Job struct{}
+
+func (j Job) Run(ctx stdctx.Context) error { return nil }
+`,
+		Filename: "job.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf, pf2})
+
+	ref := func(idx int) *parser.ParsedNonNativeType {
+		return &parser.ParsedNonNativeType{
+			Name: pf.Declarations[idx].Name,
+			Ref:  &pf.Declarations[idx],
+		}
+	}
+	names := func(entries []parser.MethodSetEntry) string {
+		s := make([]string, 0)
+		for _, entry := range entries {
+			s = append(s, strings.Join(append(entry.Path, entry.Name), "."))
+		}
+		return strings.Join(s, " ")
+	}
+
+	if s := names(parser.MethodSet(ref(3))); s != "Base.Name" {
+		t.Fatalf("wrong method set of Entity: %v", s)
+	}
+	if s := names(parser.MethodSet(&parser.ParsedPointer{ToType: ref(3)})); s != "Base.Name Save" {
+		t.Fatalf("wrong method set of *Entity: %v", s)
+	}
+	if s := names(parser.MethodSet(ref(1))); s != "Name Save" {
+		t.Fatalf("wrong method set of Store: %v", s)
+	}
+	if s := names(pf.Declarations[3].MethodSet(false)); s != "Base.Name" {
+		t.Fatalf("wrong method set of Entity declaration: %v", s)
+	}
+	if s := names(pf.Declarations[3].MethodSet(true)); s != "Base.Name Save" {
+		t.Fatalf("wrong method set of *Entity declaration: %v", s)
+	}
+
+	// Type arguments replace the receiver type parameters
+	boxMethods := parser.MethodSet(&parser.ParsedIndex{
+		Type:    ref(4),
+		Indexes: []parser.ParsedType{&parser.ParsedNativeType{Name: "int"}},
+	})
+	if len(boxMethods) != 1 || parser.Render(boxMethods[0].Func, nil) != "func() int" {
+		t.Fatalf("wrong method set of Box[int]")
+	}
+
+	ok, mismatches := parser.Implements(ref(3), ref(1))
+	if ok || len(mismatches) != 1 || mismatches[0].Name != "Save" || !mismatches[0].PointerReceiver {
+		t.Fatalf("Entity must not implement Store")
+	}
+	if ok, _ = parser.Implements(&parser.ParsedPointer{ToType: ref(3)}, ref(1)); !ok {
+		t.Fatalf("*Entity must implement Store")
+	}
+	ok, mismatches = parser.Implements(ref(5), ref(1))
+	if ok || len(mismatches) != 2 || mismatches[1].Have == nil {
+		t.Fatalf("Other must not implement Store")
+	}
+	if !parser.AssignableTo(&parser.ParsedPointer{ToType: ref(3)}, ref(0)) || parser.AssignableTo(ref(5), ref(0)) {
+		t.Fatalf("wrong assignability to Named")
+	}
+
+	impls := parser.FindImplementations([]*parser.ParsedFile{pf}, ref(1))
+	if len(impls) != 1 || impls[0].Declaration.Name != "Entity" || !impls[0].Pointer {
+		t.Fatalf("wrong implementations of Store: %v", impls)
+	}
+	// Packages imported with a different name in each file
+	job := &parser.ParsedNonNativeType{Name: "Job", Ref: &pf2.Declarations[0]}
+	if ok, mismatches = parser.Implements(job, ref(6)); !ok {
+		t.Fatalf("Job must implement Runner: %v", mismatches)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+const HeaderSize = 4
+
+type Header struct {
+	Flag  bool
+	ID    int64
+	Magic [HeaderSize]byte
+	Name  string
+}
+
+type Pair[T any] struct {
+	A, B T
+}
+
+type Packet struct {
+	Header
+	Values Pair[int16]
+	Data   []byte
+}
+
+type Unknown struct {
+	Value external.Type
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	checkLayout := func(arch string, size int64, offsets string) {
+		l, err := pf.Declarations[2].Layout(parser.SizesFor("gc", arch))
+		if err != nil {
+			t.Fatalf("unable to compute layout for %v [err=%v]", arch, err)
+		}
+		s := make([]string, 0)
+		for _, fl := range l.Fields {
+			s = append(s, fmt.Sprintf("%v:%v", fl.Name, fl.Offset))
+		}
+		if l.Size != size || strings.Join(s, " ") != offsets {
+			t.Fatalf("wrong layout for %v: %v %v", arch, l.Size, s)
+		}
+	}
+	checkLayout("amd64", 72, "Header:0 Values:40 Data:48")
+	checkLayout("386", 40, "Header:0 Values:24 Data:28")
+
+	l, err := pf.Declarations[0].Layout(parser.SizesFor("gc", "amd64"))
+	if err != nil || l.Fields[2].Offset != 16 || l.Fields[3].Offset != 24 {
+		t.Fatalf("wrong layout of Header")
+	}
+
+	if _, err = pf.Declarations[3].Layout(parser.SizesFor("gc", "amd64")); err == nil {
+		t.Fatalf("size of unresolved types must not be computed")
+	}
+	if parser.SizesFor("gc", "unknown") != nil {
+		t.Fatalf("unexpected sizes for unknown architecture")
+	}
+}
+
+func TestPackageSetQuery(t *testing.T) {
+	files := make([]*parser.ParsedFile, 0)
+	for _, src := range []struct {
+		subDir  string
+		content string
+	}{
+		{
+			subDir: "models",
+			content: `
+package models
+
+import (
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+type User struct {
+	*common.Base
+	Address *Address
+	Home    AddressPtr
+}
+
+type Address struct {
+	Street, City string
+}
+
+type AddressPtr *Address
+`,
+		},
+		{
+			subDir: "common",
+			content: `
+package common
+
+type Base struct {
+	ID string
+}
+`,
+		},
+	} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content:  src.content,
+			Filename: "test.go",
+			Module: parser.Module{
+				Name:   "github.com/mxmauro/gofile-parser-test",
+				SubDir: src.subDir,
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		files = append(files, pf)
+	}
+
+	parser.ResolveReferences(files)
+	ps := parser.NewPackageSet(files)
+
+	pd := ps.Lookup("github.com/mxmauro/gofile-parser-test/models", "Address")
+	if pd == nil || pd != &files[0].Declarations[1] {
+		t.Fatalf("unable to lookup Address")
+	}
+
+	node, err := ps.Query("models.User.Address.City")
+	if err != nil {
+		t.Fatalf("unable to query City [err=%v]", err)
+	}
+	if field, ok := node.(*parser.ParsedField); !ok || field != &files[0].Declarations[1].Type.(*parser.ParsedStruct).Fields[0] {
+		t.Fatalf("wrong node returned for City")
+	}
+
+	node, err = ps.Query("github.com/mxmauro/gofile-parser-test/models.User.ID")
+	if err != nil {
+		t.Fatalf("unable to query ID [err=%v]", err)
+	}
+	if field, ok := node.(*parser.ParsedField); !ok || field.Names[0] != "ID" {
+		t.Fatalf("wrong node returned for ID")
+	}
+
+	node, err = ps.Query("models.User.Home.Street")
+	if err != nil {
+		t.Fatalf("unable to query Street through a named pointer [err=%v]", err)
+	}
+	if field, ok := node.(*parser.ParsedField); !ok || field.Names[0] != "Street" {
+		t.Fatalf("wrong node returned for Street")
+	}
+
+	if _, err = ps.Query("models.User.Address.Zip"); err == nil {
+		t.Fatalf("unknown fields must not be found")
+	}
+}
+
+func TestPackageSetIndex(t *testing.T) {
+	ps := parser.NewPackageSet(nil)
+	for idx := 0; idx < 2; idx++ {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content: fmt.Sprintf(`
+package main
+
+const Size%d = %d
+
+type Item%d struct {
+	Next *Item%d
+	Data [Size%d]byte
+}
+`, idx, idx+4, idx, 1-idx, 1-idx),
+			Filename: fmt.Sprintf("test%d.go", idx),
+			Module: parser.Module{
+				Name: "github.com/mxmauro/gofile-parser-test",
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		ps.Add(pf)
+	}
+
+	ps.ResolveReferences()
+
+	item0 := ps.Lookup("github.com/mxmauro/gofile-parser-test", "Item0")
+	item1 := ps.Lookup("github.com/mxmauro/gofile-parser-test", "Item1")
+	if item0 == nil || item1 == nil || len(ps.Declarations("github.com/mxmauro/gofile-parser-test")) != 2 {
+		t.Fatalf("unable to lookup declarations")
+	}
+	fields := item0.Type.(*parser.ParsedStruct).Fields
+	if fields[0].Type.(*parser.ParsedPointer).ToType.(*parser.ParsedNonNativeType).Ref != item1 {
+		t.Fatalf("Item0.Next does not reference Item1")
+	}
+	if *fields[1].Type.(*parser.ParsedArray).ParsedInt != 5 {
+		t.Fatalf("wrong size of Item0.Data")
+	}
+	if pc := ps.LookupConstant("github.com/mxmauro/gofile-parser-test", "Size1"); pc == nil || pc.Value != "5" {
+		t.Fatalf("unable to lookup Size1")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	files := make([]*parser.ParsedFile, 0)
+	for _, src := range []struct {
+		subDir  string
+		content string
+	}{
+		{
+			subDir: "models",
+			content: `
+package models
+
+import (
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+const (
+	Small = iota + 1
+	Big   = 1 << 70
+	Ratio = 1.0 / 3
+)
+
+type User struct {
+	*common.Base
+	Tags  [Small]string ` + "`json:\"tags,omitempty\"`" + `
+	Next  *User
+	Ch    <-chan int
+	Items List[User]
+	Bad   [len(Small)]int
+}
+
+type List[T any] []T
+
+func (l List[T]) First() T { return l[0] }
+`,
+		},
+		{
+			subDir: "common",
+			content: `
+package common
+
+type Base struct {
+	ID string
+}
+`,
+		},
+	} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content:  src.content,
+			Filename: "test.go",
+			Module: parser.Module{
+				Name:   "github.com/mxmauro/gofile-parser-test",
+				SubDir: src.subDir,
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		files = append(files, pf)
+	}
+
+	ps := parser.NewPackageSet(files)
+
+	// Expressions must be evaluated when references are resolved after loading
+	data, err := json.Marshal(ps)
+	if err != nil {
+		t.Fatalf("unable to marshal package set [err=%v]", err)
+	}
+	var unresolved parser.PackageSet
+	err = json.Unmarshal(data, &unresolved)
+	if err != nil {
+		t.Fatalf("unable to unmarshal package set [err=%v]", err)
+	}
+	unresolved.ResolveReferences()
+	fields := unresolved.Lookup("github.com/mxmauro/gofile-parser-test/models", "User").Type.(*parser.ParsedStruct).Fields
+	if pa := fields[1].Type.(*parser.ParsedArray); pa.ParsedInt == nil || *pa.ParsedInt != 1 {
+		t.Fatalf("array length not evaluated after loading")
+	}
+	if pa := fields[5].Type.(*parser.ParsedArray); pa.SizeError == nil || !strings.Contains(pa.SizeError.Error(), "len(Small)") {
+		t.Fatalf("wrong array length error after loading: %v", pa.SizeError)
+	}
+
+	ps.ResolveReferences()
+
+	// References to instances of generic types are linked to the generic declaration
+	inst, err := parser.Instantiate(files[0].Declarations[0].Type.(*parser.ParsedStruct).Fields[4].Type.(*parser.ParsedIndex))
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	files[0].Declarations[0].Type.(*parser.ParsedStruct).Fields[4].Type = &parser.ParsedNonNativeType{
+		Name: inst.Name,
+		Ref:  inst,
+	}
+
+	data, err = json.Marshal(ps)
+	if err != nil {
+		t.Fatalf("unable to marshal package set [err=%v]", err)
+	}
+	if !strings.Contains(string(data), `"kind":"pointer"`) ||
+		!strings.Contains(string(data), `"ref":"github.com/mxmauro/gofile-parser-test/common.Base"`) {
+		t.Fatalf("wrong encoding: %v", string(data))
+	}
+
+	var loaded parser.PackageSet
+	err = json.Unmarshal(data, &loaded)
+	if err != nil {
+		t.Fatalf("unable to unmarshal package set [err=%v]", err)
+	}
+
+	user := loaded.Lookup("github.com/mxmauro/gofile-parser-test/models", "User")
+	base := loaded.Lookup("github.com/mxmauro/gofile-parser-test/common", "Base")
+	list := loaded.Lookup("github.com/mxmauro/gofile-parser-test/models", "List")
+	if user == nil || base == nil || list == nil {
+		t.Fatalf("declarations not found after loading")
+	}
+	if parser.Render(user.Type, nil) != parser.Render(files[0].Declarations[0].Type, nil) {
+		t.Fatalf("wrong User type after loading: %v", parser.Render(user.Type, nil))
+	}
+
+	// References to instances of generic types are instantiated again
+	fields = user.Type.(*parser.ParsedStruct).Fields
+	items := fields[4].Type.(*parser.ParsedNonNativeType)
+	if items.Ref == nil || items.Ref == list || items.Ref.Name != inst.Name {
+		t.Fatalf("reference to List[User] not re-linked")
+	}
+	if items.Ref.Type.(*parser.ParsedArray).ValueType.(*parser.ParsedNonNativeType).Ref != user {
+		t.Fatalf("wrong List[User] instance after loading: %v", parser.Render(items.Ref.Type, nil))
+	}
+	for _, tc := range []struct {
+		arg      *parser.ParsedDeclaration
+		expected bool
+	}{
+		{user, true},
+		{base, false},
+	} {
+		other, err := parser.Instantiate(&parser.ParsedIndex{
+			Type:    &parser.ParsedNonNativeType{Name: "List", Ref: list},
+			Indexes: []parser.ParsedType{&parser.ParsedNonNativeType{Name: tc.arg.Name, Ref: tc.arg}},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		if parser.Identical(items, &parser.ParsedNonNativeType{Name: other.Name, Ref: other}) != tc.expected {
+			t.Fatalf("wrong identity of %v and List[%v] after loading", items.Name, tc.arg.Name)
+		}
+	}
+	if fields[0].Type.(*parser.ParsedPointer).ToType.(*parser.ParsedNonNativeType).Ref != base {
+		t.Fatalf("reference to Base not re-linked")
+	}
+	if fields[2].Type.(*parser.ParsedPointer).ToType.(*parser.ParsedNonNativeType).Ref != user {
+		t.Fatalf("reference to User not re-linked")
+	}
+	if *fields[1].Type.(*parser.ParsedArray).ParsedInt != 1 || fields[1].Tags["json"] != "tags,omitempty" {
+		t.Fatalf("wrong Tags field after loading")
+	}
+	if ptpr := list.Type.(*parser.ParsedArray).ValueType.(*parser.ParsedTypeParamRef); ptpr.Param != &list.TypeParams[0] {
+		t.Fatalf("type parameter not re-linked")
+	}
+	if len(list.Methods) != 1 || list.Methods[0].Name != "First" {
+		t.Fatalf("methods not re-attached")
+	}
+
+	for idx, pc := range loaded.Packages["github.com/mxmauro/gofile-parser-test/models"][0].Constants {
+		if pc.ParsedValue == nil || pc.ParsedValue.ExactString() != files[0].Constants[idx].ParsedValue.ExactString() {
+			t.Fatalf("wrong value of constant %v after loading", pc.Name)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	for _, tc := range []struct {
+		data     string
+		expected string
+	}{
+		{
+			// Invalid constraint of a type parameter
+			data: `{"module":{"name":"x"},"declarations":[{"name":"List","typeParams":[{"names":["T"],` +
+				`"type":{"kind":"bogus"}}],"type":{"kind":"slice","elem":{"kind":"type-param","name":"T"}}}]}`,
+			expected: "unable to decode declaration List [err=unknown kind bogus]",
+		},
+		{
+			// Instance that is not an index expression
+			data: `{"module":{"name":"x"},"declarations":[{"name":"A","type":{"kind":"non-native",` +
+				`"name":"List[int]","instance":{"kind":"native","name":"int"}}}]}`,
+			expected: "unable to decode declaration A [err=instance List[int] is not an index expression]",
+		},
+		{
+			// Instance with a wrong number of type arguments
+			data: `{"module":{"name":"x"},"declarations":[{"name":"List","typeParams":[{"names":["T"],` +
+				`"type":{"kind":"non-native","name":"any"}}],"type":{"kind":"slice","elem":{"kind":"type-param",` +
+				`"name":"T"}}},{"name":"A","type":{"kind":"non-native","name":"List[int, int]","instance":` +
+				`{"kind":"index","elem":{"kind":"non-native","name":"List","ref":"x.List"},"indexes":` +
+				`[{"kind":"native","name":"int"},{"kind":"native","name":"int"}]}}}]}`,
+			expected: "unable to instantiate List[int, int] [err=too many type arguments for List]",
+		},
+	} {
+		var pf parser.ParsedFile
+
+		err := json.Unmarshal([]byte(tc.data), &pf)
+		if err == nil || err.Error() != tc.expected {
+			t.Fatalf("wrong error: %v", err)
+		}
+	}
 }
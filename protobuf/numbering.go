@@ -0,0 +1,145 @@
+package protobuf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	maxFieldNumber           = 1<<29 - 1
+	firstReservedFieldNumber = 19000
+	lastReservedFieldNumber  = 19999
+)
+
+// Numbering keeps the field numbers assigned to each message so they do not change between runs.
+// Numbers of removed fields are kept, so they are never reused, and emitted as reserved.
+type Numbering struct {
+	Messages map[string]map[string]int `json:"messages"` // Message name -> field name -> number
+}
+
+// -----------------------------------------------------------------------------
+
+// NewNumbering creates an empty numbering
+func NewNumbering() *Numbering {
+	return &Numbering{
+		Messages: make(map[string]map[string]int),
+	}
+}
+
+// ReadNumbering loads a numbering file. If the file does not exist, an empty numbering is returned.
+func ReadNumbering(filename string) (*Numbering, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return NewNumbering(), nil
+		}
+		return nil, err
+	}
+
+	n := NewNumbering()
+	err = json.Unmarshal(data, n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid numbering file %s [err=%v]", filename, err)
+	}
+	if n.Messages == nil {
+		n.Messages = make(map[string]map[string]int)
+	}
+
+	// Done
+	return n, nil
+}
+
+// WriteFile saves the numbering so it can be reused in the next run
+func (n *Numbering) WriteFile(filename string) error {
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// assign sets the number of each field of a message. Numbers given by tags are kept, then the
+// numbers stored in the numbering are reused and, finally, new numbers are allocated after the
+// highest one ever used. It returns the numbers of removed fields.
+func (n *Numbering) assign(message string, fields []*Field, tagged map[*Field]struct{}) ([]int, error) {
+	history, ok := n.Messages[message]
+	if !ok {
+		history = make(map[string]int)
+		n.Messages[message] = history
+	}
+
+	used := make(map[int]string)
+	for _, f := range fields {
+		if _, ok = tagged[f]; !ok {
+			continue
+		}
+		if other, dup := used[f.Number]; dup {
+			return nil, fmt.Errorf("fields %s and %s of message %s have the same number %d", other, f.Name, message, f.Number)
+		}
+		used[f.Number] = f.Name
+	}
+
+	for _, f := range fields {
+		if _, ok = tagged[f]; ok {
+			continue
+		}
+		if num, found := history[f.Name]; found {
+			if _, taken := used[num]; !taken {
+				f.Number = num
+				used[num] = f.Name
+			}
+		}
+	}
+
+	highest := 0
+	for num := range used {
+		if num > highest {
+			highest = num
+		}
+	}
+	for _, num := range history {
+		if num > highest {
+			highest = num
+		}
+	}
+	for _, f := range fields {
+		if f.Number != 0 {
+			continue
+		}
+		highest += 1
+		if highest >= firstReservedFieldNumber && highest <= lastReservedFieldNumber {
+			highest = lastReservedFieldNumber + 1
+		}
+		if highest > maxFieldNumber {
+			return nil, fmt.Errorf("too many fields in message %s", message)
+		}
+		f.Number = highest
+		used[highest] = f.Name
+	}
+
+	// Update the history and collect the numbers of removed fields
+	current := make(map[string]struct{})
+	for _, f := range fields {
+		history[f.Name] = f.Number
+		current[f.Name] = struct{}{}
+	}
+	reserved := make([]int, 0)
+	for name, num := range history {
+		if _, ok = current[name]; ok {
+			continue
+		}
+		if _, taken := used[num]; !taken {
+			used[num] = name
+			reserved = append(reserved, num)
+		}
+	}
+	sort.Ints(reserved)
+
+	// Done
+	return reserved, nil
+}
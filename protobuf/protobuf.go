@@ -0,0 +1,491 @@
+package protobuf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+// DefaultTagKey is the struct tag key used to set the number of a field, for example,
+// `proto:"3"`. A `name` property overrides the field name, for example, `proto:"3,name=uid"`.
+const DefaultTagKey = "proto"
+
+type Options struct {
+	// Package is the name of the proto package, defaults to the Go package name
+	Package string
+
+	// GoPackage, if set, is emitted as the go_package option
+	GoPackage string
+
+	// TagKey is the struct tag key that contains the field number, defaults to DefaultTagKey
+	TagKey string
+
+	// Numbering, if set, provides the numbers of fields without a tag. New assignments are
+	// added to it so it can be saved and reused in the next run.
+	Numbering *Numbering
+
+	// ProtoFile returns the .proto file to import for messages of another Go package. It defaults
+	// to `<import path>/<package name>.proto`.
+	ProtoFile func(importPath string, packageName string) string
+}
+
+// File is a proto3 file
+type File struct {
+	Package   string
+	GoPackage string
+	Imports   []string
+	Messages  []*Message
+}
+
+// Message is a proto3 message definition
+type Message struct {
+	Name     string
+	Comment  string
+	Fields   []*Field
+	Reserved []int
+}
+
+// Field is a field of a message
+type Field struct {
+	Name     string
+	Number   int
+	Type     string // Scalar, message or map type, like int64, common.Base or map<string, int32>
+	Repeated bool
+	Optional bool
+	Comment  string
+}
+
+type generator struct {
+	opts       Options
+	importPath string
+	file       *File
+	messages   map[string]*Message
+	queue      []queuedMessage
+	imports    map[string]struct{}
+	expanding  map[*parser.ParsedDeclaration]struct{}
+}
+
+type queuedMessage struct {
+	name string
+	ps   *parser.ParsedStruct
+	doc  string
+}
+
+type fieldType struct {
+	name     string
+	repeated bool
+	optional bool
+}
+
+// -----------------------------------------------------------------------------
+
+// Generate converts the given struct declarations, and the structs of the same package they
+// reference, to proto3 messages. References must be resolved before calling this function.
+func Generate(decls []*parser.ParsedDeclaration, opts Options) (*File, error) {
+	if len(decls) == 0 {
+		return nil, fmt.Errorf("no declarations to convert")
+	}
+	pf := decls[0].File()
+	if pf == nil {
+		return nil, fmt.Errorf("declaration %s does not belong to a parsed file", decls[0].Name)
+	}
+
+	if len(opts.TagKey) == 0 {
+		opts.TagKey = DefaultTagKey
+	}
+	if len(opts.Package) == 0 {
+		opts.Package = pf.Package
+	}
+	if opts.ProtoFile == nil {
+		opts.ProtoFile = func(importPath string, packageName string) string {
+			return importPath + "/" + packageName + ".proto"
+		}
+	}
+
+	g := generator{
+		opts:       opts,
+		importPath: pf.Module.FullName(),
+		file: &File{
+			Package:   opts.Package,
+			GoPackage: opts.GoPackage,
+			Imports:   make([]string, 0),
+			Messages:  make([]*Message, 0),
+		},
+		messages:  make(map[string]*Message),
+		queue:     make([]queuedMessage, 0),
+		imports:   make(map[string]struct{}),
+		expanding: make(map[*parser.ParsedDeclaration]struct{}),
+	}
+	if g.opts.Numbering == nil {
+		g.opts.Numbering = NewNumbering()
+	}
+
+	for _, pd := range decls {
+		if len(pd.TypeParams) > 0 {
+			return nil, fmt.Errorf("generic declaration %s must be instantiated", pd.Name)
+		}
+		ps, ok := parser.Underlying(pd.Type).(*parser.ParsedStruct)
+		if !ok {
+			return nil, fmt.Errorf("declaration %s is not a struct", pd.Name)
+		}
+		g.enqueue(pd.Name, ps, pd.Doc)
+	}
+
+	for len(g.queue) > 0 {
+		qm := g.queue[0]
+		g.queue = g.queue[1:]
+
+		err := g.message(qm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Done
+	return g.file, nil
+}
+
+// String returns the content of the .proto file
+func (f *File) String() string {
+	sb := strings.Builder{}
+
+	sb.WriteString("syntax = \"proto3\";\n")
+	if len(f.Package) > 0 {
+		sb.WriteString("\npackage " + f.Package + ";\n")
+	}
+	if len(f.Imports) > 0 {
+		sb.WriteString("\n")
+		for _, imp := range f.Imports {
+			sb.WriteString("import " + strconv.Quote(imp) + ";\n")
+		}
+	}
+	if len(f.GoPackage) > 0 {
+		sb.WriteString("\noption go_package = " + strconv.Quote(f.GoPackage) + ";\n")
+	}
+
+	for _, m := range f.Messages {
+		sb.WriteString("\n")
+		writeComment(&sb, m.Comment, "")
+		sb.WriteString("message " + m.Name + " {\n")
+		if len(m.Reserved) > 0 {
+			nums := make([]string, 0, len(m.Reserved))
+			for _, num := range m.Reserved {
+				nums = append(nums, strconv.Itoa(num))
+			}
+			sb.WriteString("  reserved " + strings.Join(nums, ", ") + ";\n")
+		}
+		for _, field := range m.Fields {
+			writeComment(&sb, field.Comment, "  ")
+			sb.WriteString("  ")
+			if field.Repeated {
+				sb.WriteString("repeated ")
+			} else if field.Optional {
+				sb.WriteString("optional ")
+			}
+			sb.WriteString(fmt.Sprintf("%s %s = %d;\n", field.Type, field.Name, field.Number))
+		}
+		sb.WriteString("}\n")
+	}
+
+	// Done
+	return sb.String()
+}
+
+// -----------------------------------------------------------------------------
+
+func (g *generator) enqueue(name string, ps *parser.ParsedStruct, doc string) {
+	if _, ok := g.messages[name]; ok {
+		return
+	}
+	m := &Message{
+		Name:    name,
+		Comment: doc,
+	}
+	g.messages[name] = m
+	g.file.Messages = append(g.file.Messages, m)
+	g.queue = append(g.queue, queuedMessage{
+		name: name,
+		ps:   ps,
+		doc:  doc,
+	})
+}
+
+func (g *generator) message(qm queuedMessage) error {
+	m := g.messages[qm.name]
+	m.Fields = make([]*Field, 0)
+	tagged := make(map[*Field]struct{})
+	names := make(map[string]string)
+
+	for _, ff := range qm.ps.FlattenedFields() {
+		if !parser.IsPublic(ff.Name) {
+			continue
+		}
+		if ff.Embedded && embeddedStruct(ff.Field.Type) {
+			continue // Fields of embedded structs are already promoted
+		}
+
+		ft, err := g.fieldType(ff.Field.Type)
+		if err != nil {
+			return fmt.Errorf("unable to convert field %s of %s [err=%v]", ff.Name, qm.name, err)
+		}
+
+		f := &Field{
+			Name:     codegen.SnakeCase(ff.Name),
+			Type:     ft.name,
+			Repeated: ft.repeated,
+			Optional: ft.optional,
+			Comment:  ff.Field.Doc,
+		}
+
+		if tag, ok := ff.Field.Tags.GetTag(g.opts.TagKey); ok {
+			if tag == "-" {
+				continue
+			}
+			number, props := codegen.SplitTag(tag)
+			if len(number) > 0 {
+				f.Number, err = strconv.Atoi(number)
+				if err != nil || f.Number < 1 || f.Number > maxFieldNumber ||
+					(f.Number >= firstReservedFieldNumber && f.Number <= lastReservedFieldNumber) {
+					return fmt.Errorf("invalid number %s for field %s of %s", number, ff.Name, qm.name)
+				}
+				tagged[f] = struct{}{}
+			}
+			if name, found := props.GetProperty("name"); found && len(name) > 0 {
+				f.Name = name
+			}
+		}
+
+		if other, ok := names[f.Name]; ok {
+			return fmt.Errorf("fields %s and %s of %s are both named %s, rename one with the name property",
+				other, ff.Name, qm.name, f.Name)
+		}
+		names[f.Name] = ff.Name
+
+		m.Fields = append(m.Fields, f)
+	}
+
+	reserved, err := g.opts.Numbering.assign(qm.name, m.Fields, tagged)
+	if err != nil {
+		return err
+	}
+	m.Reserved = reserved
+
+	// Done
+	return nil
+}
+
+func (g *generator) fieldType(t parser.ParsedType) (fieldType, error) {
+	t = parser.Unalias(t)
+
+	switch tType := t.(type) {
+	case *parser.ParsedPointer:
+		ft, err := g.fieldType(tType.ToType)
+		if err != nil {
+			return fieldType{}, err
+		}
+		if ft.repeated || strings.HasPrefix(ft.name, "map<") {
+			return fieldType{}, fmt.Errorf("pointers to repeated fields or maps are not supported")
+		}
+		ft.optional = true
+		return ft, nil
+
+	case *parser.ParsedArray:
+		if codegen.IsByte(tType.ValueType) {
+			return fieldType{
+				name: "bytes",
+			}, nil
+		}
+		ft, err := g.elementType(tType.ValueType)
+		if err != nil {
+			return fieldType{}, err
+		}
+		return fieldType{
+			name:     ft,
+			repeated: true,
+		}, nil
+
+	case *parser.ParsedMap:
+		key, err := g.elementType(tType.KeyType)
+		if err != nil {
+			return fieldType{}, err
+		}
+		switch key {
+		case "string", "bool", "int32", "int64", "uint32", "uint64", "sint32", "sint64", "fixed32", "fixed64",
+			"sfixed32", "sfixed64":
+		default:
+			return fieldType{}, fmt.Errorf("invalid map key type %s", key)
+		}
+		value, err := g.elementType(tType.ValueType)
+		if err != nil {
+			return fieldType{}, err
+		}
+		return fieldType{
+			name: "map<" + key + ", " + value + ">",
+		}, nil
+	}
+
+	name, err := g.namedType(t)
+	if err != nil {
+		return fieldType{}, err
+	}
+	return fieldType{
+		name: name,
+	}, nil
+}
+
+// elementType returns the type of a repeated field or a map key or value, which cannot be
+// repeated, optional or maps themselves
+func (g *generator) elementType(t parser.ParsedType) (string, error) {
+	ft, err := g.fieldType(t)
+	if err != nil {
+		return "", err
+	}
+	if ft.repeated || strings.HasPrefix(ft.name, "map<") {
+		return "", fmt.Errorf("nested repeated fields and maps are not supported")
+	}
+	if ft.optional {
+		// Message types already have presence, pointers to scalars cannot be represented
+		if isScalar(ft.name) {
+			return "", fmt.Errorf("pointers to scalars cannot be used in repeated fields or maps")
+		}
+	}
+	return ft.name, nil
+}
+
+// namedType returns the scalar or message type of a native type, a declared type or a generic
+// instance
+func (g *generator) namedType(t parser.ParsedType) (string, error) {
+	switch tType := t.(type) {
+	case *parser.ParsedNativeType:
+		return scalarType(tType.Name)
+
+	case *parser.ParsedNonNativeType:
+		if wkt, ok := g.wellKnownType(codegen.ID(tType)); ok {
+			return wkt, nil
+		}
+		if tType.Ref == nil {
+			return "", fmt.Errorf("unresolved reference to %s", tType.Name)
+		}
+		pd := tType.Ref
+		if len(pd.TypeParams) > 0 {
+			return "", fmt.Errorf("generic type %s must be instantiated", pd.Name)
+		}
+		return g.declaredType(pd, pd.Name)
+
+	case *parser.ParsedIndex:
+		pd, err := parser.Instantiate(tType)
+		if err != nil {
+			return "", fmt.Errorf("unable to instantiate %s [err=%v]", parser.Render(tType, nil), err)
+		}
+		return g.declaredType(pd, codegen.TypeName(pd.Name))
+	}
+
+	return "", fmt.Errorf("type %s cannot be represented in protobuf", parser.Render(t, nil))
+}
+
+func (g *generator) declaredType(pd *parser.ParsedDeclaration, name string) (string, error) {
+	ps, ok := parser.Underlying(pd.Type).(*parser.ParsedStruct)
+	if !ok {
+		// Use the representation of the underlying type, like string for `type ID string`
+		underlying := parser.Underlying(pd.Type)
+		if underlying == nil {
+			return "", fmt.Errorf("invalid recursive type %s", pd.Name)
+		}
+		if _, ok = g.expanding[pd]; ok {
+			return "", fmt.Errorf("invalid recursive type %s", pd.Name)
+		}
+		g.expanding[pd] = struct{}{}
+		defer delete(g.expanding, pd)
+
+		ft, err := g.fieldType(underlying)
+		if err != nil {
+			return "", err
+		}
+		if ft.repeated || ft.optional || strings.HasPrefix(ft.name, "map<") {
+			return "", fmt.Errorf("type %s must be used directly", pd.Name)
+		}
+		return ft.name, nil
+	}
+
+	pf := pd.File()
+	if pf != nil && pf.Module.FullName() != g.importPath {
+		// Message of another package
+		g.addImport(g.opts.ProtoFile(pf.Module.FullName(), pf.Package))
+		return pf.Package + "." + name, nil
+	}
+
+	g.enqueue(name, ps, pd.Doc)
+	return name, nil
+}
+
+func (g *generator) wellKnownType(id string) (string, bool) {
+	switch id {
+	case "time.Time":
+		g.addImport("google/protobuf/timestamp.proto")
+		return "google.protobuf.Timestamp", true
+	case "time.Duration":
+		g.addImport("google/protobuf/duration.proto")
+		return "google.protobuf.Duration", true
+	}
+	return "", false
+}
+
+func (g *generator) addImport(filename string) {
+	if _, ok := g.imports[filename]; !ok {
+		g.imports[filename] = struct{}{}
+		g.file.Imports = append(g.file.Imports, filename)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func scalarType(name string) (string, error) {
+	switch name {
+	case "bool":
+		return "bool", nil
+	case "string":
+		return "string", nil
+	case "int", "int64":
+		return "int64", nil
+	case "int8", "int16", "int32", "rune":
+		return "int32", nil
+	case "uint", "uint64", "uintptr":
+		return "uint64", nil
+	case "uint8", "uint16", "uint32", "byte":
+		return "uint32", nil
+	case "float32":
+		return "float", nil
+	case "float64":
+		return "double", nil
+	}
+	return "", fmt.Errorf("type %s cannot be represented in protobuf", name)
+}
+
+func isScalar(name string) bool {
+	switch name {
+	case "bool", "string", "bytes", "int32", "int64", "uint32", "uint64", "float", "double":
+		return true
+	}
+	return false
+}
+
+func embeddedStruct(t parser.ParsedType) bool {
+	if pp, ok := parser.Unalias(t).(*parser.ParsedPointer); ok {
+		t = pp.ToType
+	}
+	return parser.IsStruct(t)
+}
+
+func writeComment(sb *strings.Builder, comment string, indent string) {
+	if len(comment) == 0 {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		sb.WriteString(strings.TrimRight(indent+"// "+line, " ") + "\n")
+	}
+}
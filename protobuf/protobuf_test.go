@@ -0,0 +1,305 @@
+package protobuf_test
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/protobuf"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	common, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package common
+
+type Money struct {
+	Amount   int64
+	Currency string
+}
+`,
+		Filename: "common.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "common",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"time"
+
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+type ID string
+
+// Order is a purchase order
+type Order struct {
+	ID       ID                ` + "`proto:\"1\"`" + `
+	Customer *Customer
+	Lines    []Line
+	Labels   map[string]int32
+	Note     *string           ` + "`proto:\",name=comment\"`" + `
+	Total    common.Money
+	Created  time.Time
+	Payload  []byte
+	Checksum [16]byte
+	internal int
+}
+
+type Customer struct {
+	Name string
+}
+
+type Line struct {
+	SKU      string
+	Quantity uint16
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{common, pf})
+	ps.ResolveReferences()
+
+	numbering := protobuf.NewNumbering()
+	numbering.Messages["Order"] = map[string]int{
+		"customer": 7,
+		"discount": 3,
+	}
+
+	file, err := protobuf.Generate([]*parser.ParsedDeclaration{&pf.Declarations[1]}, protobuf.Options{
+		GoPackage: "example.com/orders",
+		Numbering: numbering,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate messages [err=%v]", err)
+	}
+
+	expected := `syntax = "proto3";
+
+package main;
+
+import "github.com/mxmauro/gofile-parser-test/common/common.proto";
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example.com/orders";
+
+// Order is a purchase order
+message Order {
+  reserved 3;
+  string id = 1;
+  optional Customer customer = 7;
+  repeated Line lines = 8;
+  map<string, int32> labels = 9;
+  optional string comment = 10;
+  common.Money total = 11;
+  google.protobuf.Timestamp created = 12;
+  bytes payload = 13;
+  bytes checksum = 14;
+}
+
+message Customer {
+  string name = 1;
+}
+
+message Line {
+  string sku = 1;
+  uint32 quantity = 2;
+}
+`
+	if file.String() != expected {
+		t.Fatalf("wrong proto file:\n%v", file.String())
+	}
+
+	// Numbers must not shift when fields are removed and the numbering is reused
+	pf.Declarations[1].Type.(*parser.ParsedStruct).Fields = pf.Declarations[1].Type.(*parser.ParsedStruct).Fields[2:]
+	file, err = protobuf.Generate([]*parser.ParsedDeclaration{&pf.Declarations[1]}, protobuf.Options{
+		Numbering: numbering,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate messages [err=%v]", err)
+	}
+	if !strings.Contains(file.String(), "  reserved 1, 3, 7;\n  repeated Line lines = 8;\n") {
+		t.Fatalf("wrong proto file:\n%v", file.String())
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Duplicated struct {
+	A string ` + "`proto:\"1\"`" + `
+	B string ` + "`proto:\"1\"`" + `
+}
+
+type Nested struct {
+	Matrix [][]int
+}
+
+type Callback struct {
+	Fn func()
+}
+
+type InvalidNumber struct {
+	A string ` + "`proto:\"first\"`" + `
+}
+
+type ReservedNumber struct {
+	A string ` + "`proto:\"19000\"`" + `
+}
+
+type Unresolved struct {
+	Owner Account
+}
+
+type Page[T any] struct {
+	Items []T
+}
+
+type SameName struct {
+	UserID string
+	UserId string
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	expected := []string{
+		"have the same number 1",
+		"nested repeated fields and maps are not supported",
+		"type func() cannot be represented in protobuf",
+		"invalid number first for field A of InvalidNumber",
+		"invalid number 19000 for field A of ReservedNumber",
+		"unresolved reference to Account",
+		"generic declaration Page must be instantiated",
+		"fields UserID and UserId of SameName are both named user_id",
+	}
+	for idx := range pf.Declarations {
+		_, err = protobuf.Generate([]*parser.ParsedDeclaration{&pf.Declarations[idx]}, protobuf.Options{})
+		if err == nil || !strings.Contains(err.Error(), expected[idx]) {
+			t.Fatalf("wrong error for %s: %v", pf.Declarations[idx].Name, err)
+		}
+	}
+}
+
+func TestSelfReference(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Page[T any] struct {
+	Items []T
+}
+
+type Tree struct {
+	Parent   *Tree
+	Children Page[Tree]
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	file, err := protobuf.Generate([]*parser.ParsedDeclaration{&pf.Declarations[1]}, protobuf.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate messages [err=%v]", err)
+	}
+
+	expected := `syntax = "proto3";
+
+package main;
+
+message Tree {
+  optional Tree parent = 1;
+  Page_Tree children = 2;
+}
+
+message Page_Tree {
+  repeated Tree items = 1;
+}
+`
+	if file.String() != expected {
+		t.Fatalf("wrong proto file:\n%v", file.String())
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Tree map[string]Tree
+
+type List []List
+
+type Nodes[T any] []Nodes[T]
+
+type Forest struct {
+	Trees Tree
+}
+
+type Lists struct {
+	Items List
+}
+
+type Graph struct {
+	Nodes Nodes[int]
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	// Recursive types that are not structs cannot be expanded
+	expected := []string{
+		"unable to convert field Trees of Forest [err=invalid recursive type Tree]",
+		"unable to convert field Items of Lists [err=invalid recursive type List]",
+		"unable to convert field Nodes of Graph [err=invalid recursive type Nodes[int]]",
+	}
+	for idx, pd := range pf.Declarations[3:] {
+		_, err = protobuf.Generate([]*parser.ParsedDeclaration{&pd}, protobuf.Options{})
+		if err == nil || err.Error() != expected[idx] {
+			t.Fatalf("wrong error for %s: %v", pd.Name, err)
+		}
+	}
+}
@@ -7,177 +7,173 @@ import (
 // -----------------------------------------------------------------------------
 
 type refResolver struct {
-	ModulesMap map[string][]*ParsedFile
+	packages *PackageSet
 
 	currentFile *ParsedFile
 	currentDecl *ParsedDeclaration
+
+	evaluatingConstants map[*ParsedConstant]struct{}
 }
 
 // -----------------------------------------------------------------------------
 
-// ResolveReferences tries to resolve all ParsedNonNativeType references
+// ResolveReferences tries to resolve all ParsedNonNativeType references and evaluates constants
+// and array lengths
 func ResolveReferences(parsedFiles []*ParsedFile) {
+	resolveReferences(NewPackageSet(parsedFiles))
+}
+
+func resolveReferences(ps *PackageSet) {
 	rr := refResolver{
-		ModulesMap: make(map[string][]*ParsedFile),
+		packages:            ps,
+		evaluatingConstants: make(map[*ParsedConstant]struct{}),
 	}
 
-	// Create a map of files classified by module and package
-	for _, pf := range parsedFiles {
-		moduleName := pf.Module.FullName()
+	parsedFiles := ps.files
 
-		pFiles, ok := rr.ModulesMap[moduleName]
-		if !ok {
-			pFiles = make([]*ParsedFile, 0)
+	// Attach methods to their receiver types
+	for _, pf := range parsedFiles {
+		for pdIdx := range pf.Declarations {
+			pf.Declarations[pdIdx].Methods = nil
+		}
+	}
+	for _, pf := range parsedFiles {
+		for pmIdx := range pf.Methods {
+			rr.attachMethod(pf, &pf.Methods[pmIdx])
 		}
-		rr.ModulesMap[moduleName] = append(pFiles, pf)
 	}
 
 	for _, pf := range parsedFiles {
 		rr.currentFile = pf
-		for pdIdx, pd := range pf.Declarations {
+		for pdIdx := range pf.Declarations {
 			rr.currentDecl = &pf.Declarations[pdIdx]
-			rr.resolve(pd.Type)
+			rr.resolve(rr.currentDecl)
+		}
+		rr.currentDecl = nil
+		for pcIdx := range pf.Constants {
+			pc := &pf.Constants[pcIdx]
+			rr.resolve(pc)
+			_, _ = rr.evalConstant(pf, pc)
+		}
+		for pmIdx := range pf.Methods {
+			rr.resolve(&pf.Methods[pmIdx])
 		}
 	}
 }
 
-func (rr *refResolver) resolve(t interface{}) {
-	switch tType := t.(type) {
-	case *ParsedNonNativeType:
-		rr.resolveNonNativeType(tType)
-	case *ParsedStruct:
-		rr.processStruct(tType)
-	case *ParsedInterface:
-		rr.processInterface(tType)
-	case *ParsedArray:
-		rr.processArray(tType)
-	case *ParsedMap:
-		rr.processMap(tType)
-	case *ParsedPointer:
-		rr.processPointer(tType)
-	case *ParsedChannel:
-		rr.processChannel(tType)
-	case *ParsedFunction:
-		rr.processFunction(tType)
+func (rr *refResolver) attachMethod(pf *ParsedFile, pm *ParsedMethod) {
+	if pd := rr.findDeclaration(pf, pm.Receiver); pd != nil {
+		attachMethod(pd, pm)
 	}
 }
 
-func (rr *refResolver) processStruct(ps *ParsedStruct) {
-	for _, field := range ps.Fields {
-		rr.resolve(field.Type)
-	}
-}
+// -----------------------------------------------------------------------------
+
+func attachMethod(pd *ParsedDeclaration, pm *ParsedMethod) {
+	pd.Methods = append(pd.Methods, pm)
 
-func (rr *refResolver) processInterface(pi *ParsedInterface) {
-	for _, field := range pi.Methods {
-		rr.resolve(field.Type)
+	// Receiver type parameters share the constraints of the declaration ones
+	idx := 0
+	for _, tp := range pd.TypeParams {
+		for range tp.Names {
+			if idx < len(pm.ReceiverTypeParams) {
+				pm.ReceiverTypeParams[idx].Type = tp.Type
+			}
+			idx += 1
+		}
 	}
 }
 
-func (rr *refResolver) processArray(pa *ParsedArray) {
-	rr.resolve(pa.ValueType)
+func (rr *refResolver) resolve(node interface{}) {
+	Walk(node, func(node interface{}, _ Path) bool {
+		switch n := node.(type) {
+		case *ParsedNonNativeType:
+			rr.resolveNonNativeType(n)
+		case *ParsedTypeParamRef:
+			// Bound to a type parameter when parsed, must not be looked up at package level
+		case *ParsedArray:
+			if n.ParsedInt == nil && n.sizeExpr != nil {
+				rr.evalArraySize(n)
+			}
+		}
+		return true
+	})
 }
 
-func (rr *refResolver) processMap(pm *ParsedMap) {
-	rr.resolve(pm.KeyType)
-	rr.resolve(pm.ValueType)
-}
+func (rr *refResolver) resolveNonNativeType(pnnt *ParsedNonNativeType) {
+	if pnnt.Ref != nil {
+		return // Already resolved
+	}
 
-func (rr *refResolver) processPointer(pp *ParsedPointer) {
-	rr.resolve(pp.ToType)
+	pnnt.Ref = rr.findDeclaration(rr.currentFile, pnnt.Name)
 }
 
-func (rr *refResolver) processChannel(pc *ParsedChannel) {
-	rr.resolve(pc.Type)
-}
+func (rr *refResolver) findDeclaration(pf *ParsedFile, qualifiedName string) *ParsedDeclaration {
+	packageName, objName := GetIdentifierParts(qualifiedName)
 
-func (rr *refResolver) processFunction(pf *ParsedFunction) {
-	for _, field := range pf.TypeParams {
-		rr.resolve(field.Type)
-	}
-	for _, field := range pf.Params {
-		rr.resolve(field.Type)
-	}
-	for _, field := range pf.Results {
-		rr.resolve(field.Type)
+	moduleName, ok := rr.resolvePackage(pf, packageName)
+	if !ok {
+		return nil // Unable to determine import path, let's continue
 	}
+
+	return rr.packages.Lookup(moduleName, objName)
 }
 
-func (rr *refResolver) resolveNonNativeType(pnnt *ParsedNonNativeType) {
-	if pnnt.Ref != nil {
-		return // Already resolved
+// resolvePackage returns the full module name of the package referenced by the given name inside
+// the specified file. An empty package name refers to the file's own package.
+func (rr *refResolver) resolvePackage(pf *ParsedFile, packageName string) (string, bool) {
+	// Assume the local reference by default
+	moduleName := pf.Module.FullName()
+	if len(packageName) == 0 {
+		return moduleName, true
 	}
 
-	moduleName := rr.currentFile.Module.FullName()
-
-	// Assume the local reference by default
-	objName := pnnt.Name
-
-	dotIdx := strings.Index(pnnt.Name, ".")
-	if dotIdx >= 0 {
-		// Find the import
-		importedPackageName := pnnt.Name[:dotIdx]
-		objName = objName[(dotIdx + 1):]
-
-		importPath := ""
-		for _, pi := range rr.currentFile.Imports {
-			if pi.Name == importedPackageName {
-				importPath = pi.Path
-				break
-			}
-		}
-		if len(importPath) == 0 {
-			return // Unable to determine import path, let's continue
+	// Find the import
+	importPath := ""
+	for _, pi := range pf.Imports {
+		if pi.PackageName() == packageName {
+			importPath = pi.Path
+			break
 		}
+	}
+	if len(importPath) == 0 {
+		return "", false
+	}
 
-		if importPath != "." {
-			if strings.HasPrefix(importPath, ".") {
-				// Assume a relative path
-				tempPath := importPath
-				if len(rr.currentFile.Module.SubDir) > 0 {
-					tempPath = rr.currentFile.Module.SubDir + "/" + tempPath
-				}
+	if importPath != "." {
+		if strings.HasPrefix(importPath, ".") {
+			// Assume a relative path
+			tempPath := importPath
+			if len(pf.Module.SubDir) > 0 {
+				tempPath = pf.Module.SubDir + "/" + tempPath
+			}
 
-				fragments := strings.Split(tempPath, "/")
-				idx := 0
-				for idx < len(fragments) {
-					if fragments[idx] == "." {
-						fragments = append(fragments[0:idx], fragments[(idx+1):]...)
-					} else if fragments[idx] == ".." {
-						if idx == 0 {
-							return // Invalid path
-						}
-						fragments = append(fragments[0:(idx-1)], fragments[(idx+1):]...)
-						idx -= 1
-					} else {
-						idx += 1
+			fragments := strings.Split(tempPath, "/")
+			idx := 0
+			for idx < len(fragments) {
+				if fragments[idx] == "." {
+					fragments = append(fragments[0:idx], fragments[(idx+1):]...)
+				} else if fragments[idx] == ".." {
+					if idx == 0 {
+						return "", false // Invalid path
 					}
+					fragments = append(fragments[0:(idx-1)], fragments[(idx+1):]...)
+					idx -= 1
+				} else {
+					idx += 1
 				}
-
-				moduleName = rr.currentFile.Module.Name
-				if len(fragments) > 0 {
-					moduleName += "/" + strings.Join(fragments, "/")
-				}
-
-			} else {
-				moduleName = importPath
 			}
-		}
-	}
-
-	parsedFilesToCheck, ok := rr.ModulesMap[moduleName]
-	if (!ok) || len(parsedFilesToCheck) == 0 {
-		return // Unable to find other modules
-	}
 
-	for _, pf2c := range parsedFilesToCheck {
-		for pd2cIdx := range pf2c.Declarations {
-			pd2c := &pf2c.Declarations[pd2cIdx]
-			if pd2c.Name == objName {
-				// Found the reference
-				pnnt.Ref = pd2c
-				return
+			moduleName = pf.Module.Name
+			if len(fragments) > 0 {
+				moduleName += "/" + strings.Join(fragments, "/")
 			}
+
+		} else {
+			moduleName = importPath
 		}
 	}
+
+	// Done
+	return moduleName, true
 }
@@ -0,0 +1,342 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// Qualifier returns the name used to qualify identifiers declared in the package with the given
+// import path. An empty string means identifiers of that package are not qualified.
+type Qualifier func(importPath string) string
+
+type renderer struct {
+	sb        strings.Builder
+	qualifier Qualifier
+}
+
+// -----------------------------------------------------------------------------
+
+// Render returns the Go source text of the given type.
+//
+// If a qualifier is specified, it is called for each named type with a known import path, so the
+// type can be printed relative to another package or using different import names. Array lengths
+// are printed using their evaluated value in this case. If the qualifier is nil, the names are
+// printed as they were written in the source code.
+func Render(t ParsedType, qualifier Qualifier) string {
+	r := renderer{
+		qualifier: qualifier,
+	}
+	r.renderType(t)
+	return r.sb.String()
+}
+
+func (r *renderer) renderType(t ParsedType) {
+	switch tType := t.(type) {
+	case *ParsedNativeType:
+		r.sb.WriteString(tType.Name)
+
+	case *ParsedNonNativeType:
+		r.renderNonNativeType(tType)
+
+	case *ParsedTypeParamRef:
+		r.sb.WriteString(tType.Name)
+
+	case *ParsedStruct:
+		r.renderStruct(tType)
+
+	case *ParsedInterface:
+		r.renderInterface(tType)
+
+	case *ParsedMap:
+		r.sb.WriteString("map[")
+		r.renderType(tType.KeyType)
+		r.sb.WriteString("]")
+		r.renderType(tType.ValueType)
+
+	case *ParsedArray:
+		r.sb.WriteString("[")
+		if r.qualifier != nil && tType.ParsedInt != nil {
+			r.sb.WriteString(strconv.FormatInt(*tType.ParsedInt, 10))
+		} else {
+			r.sb.WriteString(tType.Size)
+		}
+		r.sb.WriteString("]")
+		r.renderType(tType.ValueType)
+
+	case *ParsedPointer:
+		r.sb.WriteString("*")
+		r.renderType(tType.ToType)
+
+	case *ParsedChannel:
+		r.renderChannel(tType)
+
+	case *ParsedFunction:
+		r.sb.WriteString("func")
+		r.renderSignature(tType)
+
+	case *ParsedIndex:
+		r.renderType(tType.Type)
+		r.sb.WriteString("[")
+		for idx, index := range tType.Indexes {
+			if idx > 0 {
+				r.sb.WriteString(", ")
+			}
+			r.renderType(index)
+		}
+		r.sb.WriteString("]")
+
+	case *ParsedUnion:
+		for idx, term := range tType.Terms {
+			if idx > 0 {
+				r.sb.WriteString(" | ")
+			}
+			if term.Tilde {
+				r.sb.WriteString("~")
+			}
+			r.renderType(term.Type)
+		}
+	}
+}
+
+func (r *renderer) renderNonNativeType(pnnt *ParsedNonNativeType) {
+	if r.qualifier != nil {
+		importPath := pnnt.ImportPath
+		if pnnt.Ref != nil && pnnt.Ref.file != nil {
+			importPath = pnnt.Ref.file.Module.FullName()
+		}
+
+		if len(importPath) > 0 || pnnt.Ref != nil {
+			// Names of generic instances include their type arguments
+			name, typeArgs := pnnt.Name, ""
+			if idx := strings.Index(name, "["); idx >= 0 {
+				name, typeArgs = name[:idx], name[idx:]
+			}
+			_, name = GetIdentifierParts(name)
+			if packageName := r.qualifier(importPath); len(packageName) > 0 {
+				r.sb.WriteString(packageName + ".")
+			}
+			r.sb.WriteString(name + typeArgs)
+			return
+		}
+	}
+
+	r.sb.WriteString(pnnt.Name)
+}
+
+func (r *renderer) renderStruct(ps *ParsedStruct) {
+	r.sb.WriteString("struct{")
+	for idx := range ps.Fields {
+		field := &ps.Fields[idx]
+
+		if idx > 0 {
+			r.sb.WriteString("; ")
+		}
+		if len(field.Names) > 0 {
+			r.sb.WriteString(strings.Join(field.Names, ", "))
+			r.sb.WriteString(" ")
+		}
+		r.renderType(field.Type)
+
+		if len(field.rawTag) > 0 {
+			r.sb.WriteString(" ")
+			if strings.Contains(field.rawTag, "`") {
+				r.sb.WriteString(strconv.Quote(field.rawTag))
+			} else {
+				r.sb.WriteString("`" + field.rawTag + "`")
+			}
+		}
+	}
+	r.sb.WriteString("}")
+}
+
+func (r *renderer) renderInterface(pi *ParsedInterface) {
+	r.sb.WriteString("interface{")
+	for idx := range pi.Methods {
+		method := &pi.Methods[idx]
+
+		if idx > 0 {
+			r.sb.WriteString("; ")
+		}
+		if pfunc, ok := method.Type.(*ParsedFunction); ok && len(method.Names) > 0 {
+			r.sb.WriteString(method.Names[0])
+			r.renderSignature(pfunc)
+		} else {
+			r.renderType(method.Type)
+		}
+	}
+	r.sb.WriteString("}")
+}
+
+func (r *renderer) renderChannel(pc *ParsedChannel) {
+	switch pc.Dir {
+	case ast.SEND:
+		r.sb.WriteString("chan<- ")
+	case ast.RECV:
+		r.sb.WriteString("<-chan ")
+	default:
+		r.sb.WriteString("chan ")
+	}
+
+	// A receive-only channel as element of a bidirectional one must be parenthesized
+	if elemChan, ok := pc.Type.(*ParsedChannel); ok && elemChan.Dir == ast.RECV && pc.Dir != ast.SEND && pc.Dir != ast.RECV {
+		r.sb.WriteString("(")
+		r.renderType(pc.Type)
+		r.sb.WriteString(")")
+		return
+	}
+	r.renderType(pc.Type)
+}
+
+func (r *renderer) renderSignature(pfunc *ParsedFunction) {
+	if len(pfunc.TypeParams) > 0 {
+		r.sb.WriteString("[")
+		r.renderFieldList(pfunc.TypeParams, false)
+		r.sb.WriteString("]")
+	}
+
+	r.sb.WriteString("(")
+	r.renderFieldList(pfunc.Params, pfunc.Variadic)
+	r.sb.WriteString(")")
+
+	switch {
+	case len(pfunc.Results) == 1 && len(pfunc.Results[0].Names) == 0:
+		r.sb.WriteString(" ")
+		r.renderType(pfunc.Results[0].Type)
+
+	case len(pfunc.Results) > 0:
+		r.sb.WriteString(" (")
+		r.renderFieldList(pfunc.Results, false)
+		r.sb.WriteString(")")
+	}
+}
+
+func (r *renderer) renderFieldList(fields []ParsedField, variadic bool) {
+	for idx := range fields {
+		field := &fields[idx]
+
+		if idx > 0 {
+			r.sb.WriteString(", ")
+		}
+		if len(field.Names) > 0 {
+			r.sb.WriteString(strings.Join(field.Names, ", "))
+			r.sb.WriteString(" ")
+		}
+
+		if pa, ok := field.Type.(*ParsedArray); ok && variadic && idx == len(fields)-1 && len(pa.Size) == 0 {
+			r.sb.WriteString("...")
+			r.renderType(pa.ValueType)
+		} else {
+			r.renderType(field.Type)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (pnt *ParsedNativeType) String() string {
+	return Render(pnt, nil)
+}
+
+func (pnnt *ParsedNonNativeType) String() string {
+	return Render(pnnt, nil)
+}
+
+func (ptpr *ParsedTypeParamRef) String() string {
+	return Render(ptpr, nil)
+}
+
+func (ps *ParsedStruct) String() string {
+	return Render(ps, nil)
+}
+
+func (pi *ParsedInterface) String() string {
+	return Render(pi, nil)
+}
+
+func (pm *ParsedMap) String() string {
+	return Render(pm, nil)
+}
+
+func (pa *ParsedArray) String() string {
+	return Render(pa, nil)
+}
+
+func (pp *ParsedPointer) String() string {
+	return Render(pp, nil)
+}
+
+func (pc *ParsedChannel) String() string {
+	return Render(pc, nil)
+}
+
+func (pfunc *ParsedFunction) String() string {
+	return Render(pfunc, nil)
+}
+
+func (pi *ParsedIndex) String() string {
+	return Render(pi, nil)
+}
+
+func (pu *ParsedUnion) String() string {
+	return Render(pu, nil)
+}
+
+func (pnt *ParsedNativeType) GoString() string {
+	return goString(pnt)
+}
+
+func (pnnt *ParsedNonNativeType) GoString() string {
+	return goString(pnnt)
+}
+
+func (ptpr *ParsedTypeParamRef) GoString() string {
+	return goString(ptpr)
+}
+
+func (ps *ParsedStruct) GoString() string {
+	return goString(ps)
+}
+
+func (pi *ParsedInterface) GoString() string {
+	return goString(pi)
+}
+
+func (pm *ParsedMap) GoString() string {
+	return goString(pm)
+}
+
+func (pa *ParsedArray) GoString() string {
+	return goString(pa)
+}
+
+func (pp *ParsedPointer) GoString() string {
+	return goString(pp)
+}
+
+func (pc *ParsedChannel) GoString() string {
+	return goString(pc)
+}
+
+func (pfunc *ParsedFunction) GoString() string {
+	return goString(pfunc)
+}
+
+func (pi *ParsedIndex) GoString() string {
+	return goString(pi)
+}
+
+func (pu *ParsedUnion) GoString() string {
+	return goString(pu)
+}
+
+// -----------------------------------------------------------------------------
+
+// goString returns the node type along with its source text, for example:
+// `*parser.ParsedMap("map[string]int")`.
+func goString(t ParsedType) string {
+	return fmt.Sprintf("%T(%s)", t, strconv.Quote(Render(t, nil)))
+}
@@ -0,0 +1,415 @@
+package sqlschema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	parser "github.com/mxmauro/gofile-parser"
+)
+
+// -----------------------------------------------------------------------------
+
+// Changes are sorted by kind so the generated statements can be executed in order: renames come
+// first, then new tables and columns, and dropped tables come last.
+const (
+	RenameTable ChangeKind = iota
+	DropForeignKey
+	DropIndex
+	RenameColumn
+	CreateTable
+	AddColumn
+	AlterColumnType
+	AlterColumnNullability
+	AlterColumnDefault
+	AlterColumnUnique
+	AlterPrimaryKey
+	DropColumn
+	AddForeignKey
+	AddIndex
+	DropTable
+)
+
+// ChangeKind is the kind of difference between two versions of a table
+type ChangeKind int
+
+// Change is a difference between two versions of a set of tables
+type Change struct {
+	Kind       ChangeKind
+	Table      string      // Name of the table, the previous name if the table is dropped
+	OldTable   string      // Previous name of the table, equal to Table unless it was renamed
+	Column     *Column     // New definition of the column
+	OldColumn  *Column     // Previous definition of the column
+	Index      *Index      // Created or dropped index
+	ForeignKey *ForeignKey // Created or dropped foreign key
+	PrimaryKey []string    // New primary key columns
+
+	oldDefinition *Table
+	definition    *Table
+}
+
+type columnPair struct {
+	old *Column
+	new *Column
+}
+
+// -----------------------------------------------------------------------------
+
+var changeKindNames = []string{
+	"rename table", "drop foreign key", "drop index", "rename column", "create table", "add column",
+	"alter column type", "alter column nullability", "alter column default", "alter column unique",
+	"alter primary key", "drop column", "add foreign key", "add index", "drop table",
+}
+
+// TaggedStructs returns the non-generic structs of the files that have at least one field with the
+// tag, for example, to compare the current parse of a package with a snapshot loaded from JSON
+func TaggedStructs(files []*parser.ParsedFile, tagKey string) []*parser.ParsedDeclaration {
+	if len(tagKey) == 0 {
+		tagKey = DefaultTagKey
+	}
+
+	decls := make([]*parser.ParsedDeclaration, 0)
+	for _, pf := range files {
+		for pdIdx := range pf.Declarations {
+			pd := &pf.Declarations[pdIdx]
+			if len(pd.TypeParams) > 0 || pd.IsAlias {
+				continue
+			}
+			ps, ok := pd.Type.(*parser.ParsedStruct)
+			if !ok {
+				continue
+			}
+			for _, ff := range ps.FlattenedFields() {
+				if tag, found := ff.Field.Tags.GetTag(tagKey); found && tag != "-" {
+					decls = append(decls, pd)
+					break
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// Migrate compares two versions of a set of structs and returns the statements that migrate the
+// tables of the old version to the new one. In SQLite, tables with changes that ALTER TABLE does
+// not support are rebuilt, so foreign key enforcement should be disabled while migrating. Columns
+// added to existing tables must be nullable or have a default value.
+func Migrate(oldDecls []*parser.ParsedDeclaration, newDecls []*parser.ParsedDeclaration, opts Options) (string, error) {
+	oldTables, err := Tables(oldDecls, opts)
+	if err != nil {
+		return "", err
+	}
+	newTables, err := Tables(newDecls, opts)
+	if err != nil {
+		return "", err
+	}
+
+	dialect := opts.Dialect
+	if len(dialect) == 0 {
+		dialect = PostgreSQL
+	}
+
+	changes := Diff(oldTables, newTables)
+	for _, change := range changes {
+		// Existing rows would not have a value for the new column
+		if change.Kind == AddColumn && change.Column.NotNull && len(change.Column.Default) == 0 {
+			return "", fmt.Errorf("column %s added to table %s is NOT NULL and must have a default value",
+				change.Column.Name, change.Table)
+		}
+	}
+
+	sb := strings.Builder{}
+	for _, stmt := range MigrationStatements(changes, dialect) {
+		sb.WriteString(stmt + ";\n")
+	}
+
+	// Done
+	return sb.String(), nil
+}
+
+// Diff returns the changes needed to convert the old tables into the new ones, sorted in execution
+// order. Tables are matched by the name of their Go type and columns by the Go field, so renaming a
+// table or a column in the tag is detected as a rename instead of a drop and an addition.
+func Diff(oldTables []*Table, newTables []*Table) []*Change {
+	changes := make([]*Change, 0)
+
+	matched := make(map[*Table]struct{})
+	for _, newTable := range newTables {
+		oldTable := findTable(oldTables, newTable)
+		if oldTable == nil {
+			changes = append(changes, &Change{
+				Kind:       CreateTable,
+				Table:      newTable.Name,
+				OldTable:   newTable.Name,
+				definition: newTable,
+			})
+			for _, fk := range newTable.ForeignKeys {
+				if fk.Deferred {
+					changes = append(changes, &Change{
+						Kind:       AddForeignKey,
+						Table:      newTable.Name,
+						OldTable:   newTable.Name,
+						ForeignKey: fk,
+					})
+				}
+			}
+			continue
+		}
+		matched[oldTable] = struct{}{}
+		changes = append(changes, diffTable(oldTable, newTable)...)
+	}
+
+	// Drop tables in reverse order so referencing tables are dropped first
+	for idx := len(oldTables) - 1; idx >= 0; idx-- {
+		if _, ok := matched[oldTables[idx]]; !ok {
+			changes = append(changes, &Change{
+				Kind:          DropTable,
+				Table:         oldTables[idx].Name,
+				OldTable:      oldTables[idx].Name,
+				oldDefinition: oldTables[idx],
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Kind < changes[j].Kind
+	})
+
+	// Done
+	return changes
+}
+
+// MigrationStatements converts the changes to SQL statements, without the trailing semicolon
+func MigrationStatements(changes []*Change, dialect Dialect) []string {
+	if dialect == SQLite {
+		return sqliteStatements(changes)
+	}
+	return postgresStatements(changes)
+}
+
+func (k ChangeKind) String() string {
+	if k >= 0 && int(k) < len(changeKindNames) {
+		return changeKindNames[k]
+	}
+	return "unknown"
+}
+
+// -----------------------------------------------------------------------------
+
+func findTable(tables []*Table, t *Table) *Table {
+	for _, other := range tables {
+		if len(t.Struct) > 0 && other.Struct == t.Struct {
+			return other
+		}
+	}
+	for _, other := range tables {
+		if other.Name == t.Name {
+			return other
+		}
+	}
+	return nil
+}
+
+// matchColumns pairs the columns of two versions of a table by Go field, then by name. Unmatched
+// columns have a nil counterpart.
+func matchColumns(oldTable *Table, newTable *Table) []columnPair {
+	pairs := make([]columnPair, 0, len(newTable.Columns))
+	used := make(map[*Column]struct{})
+
+	find := func(match func(col *Column) bool) *Column {
+		for _, col := range oldTable.Columns {
+			if _, ok := used[col]; !ok && match(col) {
+				used[col] = struct{}{}
+				return col
+			}
+		}
+		return nil
+	}
+
+	for _, newCol := range newTable.Columns {
+		oldCol := find(func(col *Column) bool {
+			return len(newCol.Field) > 0 && col.Field == newCol.Field
+		})
+		if oldCol == nil {
+			oldCol = find(func(col *Column) bool {
+				return col.Name == newCol.Name
+			})
+		}
+		pairs = append(pairs, columnPair{
+			old: oldCol,
+			new: newCol,
+		})
+	}
+	for _, oldCol := range oldTable.Columns {
+		if _, ok := used[oldCol]; !ok {
+			pairs = append(pairs, columnPair{
+				old: oldCol,
+			})
+		}
+	}
+	return pairs
+}
+
+func diffTable(oldTable *Table, newTable *Table) []*Change {
+	changes := make([]*Change, 0)
+	add := func(change *Change) {
+		change.Table = newTable.Name
+		change.OldTable = oldTable.Name
+		change.oldDefinition = oldTable
+		change.definition = newTable
+		changes = append(changes, change)
+	}
+
+	if oldTable.Name != newTable.Name {
+		add(&Change{
+			Kind: RenameTable,
+		})
+	}
+
+	for _, pair := range matchColumns(oldTable, newTable) {
+		switch {
+		case pair.old == nil:
+			add(&Change{
+				Kind:   AddColumn,
+				Column: pair.new,
+			})
+			continue
+		case pair.new == nil:
+			add(&Change{
+				Kind:      DropColumn,
+				OldColumn: pair.old,
+			})
+			continue
+		}
+
+		kinds := make([]ChangeKind, 0)
+		if pair.old.Name != pair.new.Name {
+			kinds = append(kinds, RenameColumn)
+		}
+		if !strings.EqualFold(pair.old.Type, pair.new.Type) {
+			kinds = append(kinds, AlterColumnType)
+		}
+		if pair.old.NotNull != pair.new.NotNull {
+			kinds = append(kinds, AlterColumnNullability)
+		}
+		if pair.old.Default != pair.new.Default {
+			kinds = append(kinds, AlterColumnDefault)
+		}
+		if pair.old.Unique != pair.new.Unique {
+			kinds = append(kinds, AlterColumnUnique)
+		}
+		for _, kind := range kinds {
+			add(&Change{
+				Kind:      kind,
+				Column:    pair.new,
+				OldColumn: pair.old,
+			})
+		}
+	}
+
+	// Primary keys, foreign keys and indexes are compared by the new column names
+	renamed := make(map[string]string)
+	for _, pair := range matchColumns(oldTable, newTable) {
+		if pair.old != nil && pair.new != nil {
+			renamed[pair.old.Name] = pair.new.Name
+		}
+	}
+	renameColumns := func(columns []string) []string {
+		result := make([]string, 0, len(columns))
+		for _, col := range columns {
+			if newName, ok := renamed[col]; ok {
+				col = newName
+			}
+			result = append(result, col)
+		}
+		return result
+	}
+
+	if !equalColumns(renameColumns(oldTable.PrimaryKey), newTable.PrimaryKey) {
+		add(&Change{
+			Kind:       AlterPrimaryKey,
+			PrimaryKey: newTable.PrimaryKey,
+		})
+	}
+
+	for _, oldFK := range oldTable.ForeignKeys {
+		if !containsForeignKey(newTable.ForeignKeys, renameColumns(oldFK.Columns), oldFK) {
+			add(&Change{
+				Kind:       DropForeignKey,
+				ForeignKey: oldFK,
+			})
+		}
+	}
+	for _, newFK := range newTable.ForeignKeys {
+		found := false
+		for _, oldFK := range oldTable.ForeignKeys {
+			if equalColumns(renameColumns(oldFK.Columns), newFK.Columns) && sameReference(oldFK, newFK) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add(&Change{
+				Kind:       AddForeignKey,
+				ForeignKey: newFK,
+			})
+		}
+	}
+
+	// Indexes are identified by name, so they are recreated if the columns change
+	for _, oldIdx := range oldTable.Indexes {
+		newIdx := findIndex(newTable.Indexes, oldIdx.Name)
+		if newIdx == nil || !equalColumns(renameColumns(oldIdx.Columns), newIdx.Columns) {
+			add(&Change{
+				Kind:  DropIndex,
+				Index: oldIdx,
+			})
+		}
+	}
+	for _, newIdx := range newTable.Indexes {
+		oldIdx := findIndex(oldTable.Indexes, newIdx.Name)
+		if oldIdx == nil || !equalColumns(renameColumns(oldIdx.Columns), newIdx.Columns) {
+			add(&Change{
+				Kind:  AddIndex,
+				Index: newIdx,
+			})
+		}
+	}
+
+	// Done
+	return changes
+}
+
+func containsForeignKey(fks []*ForeignKey, columns []string, fk *ForeignKey) bool {
+	for _, other := range fks {
+		if equalColumns(other.Columns, columns) && sameReference(other, fk) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameReference(fk1 *ForeignKey, fk2 *ForeignKey) bool {
+	return fk1.RefTable == fk2.RefTable && equalColumns(fk1.RefColumns, fk2.RefColumns)
+}
+
+func findIndex(indexes []*Index, name string) *Index {
+	for _, idx := range indexes {
+		if idx.Name == name {
+			return idx
+		}
+	}
+	return nil
+}
+
+func equalColumns(columns1 []string, columns2 []string) bool {
+	if len(columns1) != len(columns2) {
+		return false
+	}
+	for idx := range columns1 {
+		if columns1[idx] != columns2[idx] {
+			return false
+		}
+	}
+	return true
+}
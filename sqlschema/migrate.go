@@ -0,0 +1,220 @@
+package sqlschema
+
+import (
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+func postgresStatements(changes []*Change) []string {
+	stmts := make([]string, 0, len(changes))
+
+	for _, change := range changes {
+		table := QuoteIdentifier(change.Table)
+		alter := "ALTER TABLE " + table + " "
+
+		switch change.Kind {
+		case RenameTable:
+			stmts = append(stmts, "ALTER TABLE "+QuoteIdentifier(change.OldTable)+" RENAME TO "+table)
+
+		case DropForeignKey:
+			name := constraintName(change.OldTable, change.ForeignKey.Columns, "fkey")
+			stmts = append(stmts, alter+"DROP CONSTRAINT "+name)
+
+		case DropIndex:
+			stmts = append(stmts, "DROP INDEX "+QuoteIdentifier(change.Index.Name))
+
+		case RenameColumn:
+			stmts = append(stmts, alter+"RENAME COLUMN "+QuoteIdentifier(change.OldColumn.Name)+" TO "+
+				QuoteIdentifier(change.Column.Name))
+
+		case CreateTable:
+			stmts = append(stmts, change.definition.CreateStatements()...)
+
+		case AddColumn:
+			stmts = append(stmts, alter+"ADD COLUMN "+change.Column.Definition())
+
+		case AlterColumnType:
+			column := QuoteIdentifier(change.Column.Name)
+			stmts = append(stmts, alter+"ALTER COLUMN "+column+" TYPE "+change.Column.Type+" USING "+column+"::"+
+				change.Column.Type)
+
+		case AlterColumnNullability:
+			column := QuoteIdentifier(change.Column.Name)
+			if change.Column.NotNull {
+				stmts = append(stmts, alter+"ALTER COLUMN "+column+" SET NOT NULL")
+			} else {
+				stmts = append(stmts, alter+"ALTER COLUMN "+column+" DROP NOT NULL")
+			}
+
+		case AlterColumnDefault:
+			column := QuoteIdentifier(change.Column.Name)
+			if len(change.Column.Default) > 0 {
+				stmts = append(stmts, alter+"ALTER COLUMN "+column+" SET DEFAULT "+change.Column.Default)
+			} else {
+				stmts = append(stmts, alter+"ALTER COLUMN "+column+" DROP DEFAULT")
+			}
+
+		case AlterColumnUnique:
+			if change.Column.Unique {
+				name := constraintName(change.Table, []string{change.Column.Name}, "key")
+				stmts = append(stmts, alter+"ADD CONSTRAINT "+name+" UNIQUE ("+QuoteIdentifier(change.Column.Name)+")")
+			} else {
+				name := constraintName(change.OldTable, []string{change.OldColumn.Name}, "key")
+				stmts = append(stmts, alter+"DROP CONSTRAINT "+name)
+			}
+
+		case AlterPrimaryKey:
+			if change.oldDefinition != nil && len(change.oldDefinition.PrimaryKey) > 0 {
+				stmts = append(stmts, alter+"DROP CONSTRAINT "+QuoteIdentifier(change.OldTable+"_pkey"))
+			}
+			if len(change.PrimaryKey) > 0 {
+				stmts = append(stmts, alter+"ADD PRIMARY KEY ("+quoteIdentifiers(change.PrimaryKey)+")")
+			}
+
+		case DropColumn:
+			stmts = append(stmts, alter+"DROP COLUMN "+QuoteIdentifier(change.OldColumn.Name))
+
+		case AddForeignKey:
+			stmts = append(stmts, change.ForeignKey.AddStatement(change.Table))
+
+		case AddIndex:
+			stmts = append(stmts, change.Index.CreateStatement(change.Table))
+
+		case DropTable:
+			stmts = append(stmts, "DROP TABLE "+table)
+		}
+	}
+
+	// Done
+	return stmts
+}
+
+// sqliteStatements converts the changes using the ALTER TABLE statements supported by SQLite.
+// Tables with other changes are rebuilt: a new table is created, the rows are copied and the old
+// table is replaced.
+func sqliteStatements(changes []*Change) []string {
+	stmts := make([]string, 0, len(changes))
+
+	rebuild := make(map[string]bool)
+	for _, change := range changes {
+		if needsRebuild(change) {
+			rebuild[change.Table] = true
+		}
+	}
+	rebuilt := make(map[string]struct{})
+
+	for _, change := range changes {
+		table := QuoteIdentifier(change.Table)
+
+		switch change.Kind {
+		case RenameTable:
+			stmts = append(stmts, "ALTER TABLE "+QuoteIdentifier(change.OldTable)+" RENAME TO "+table)
+			continue
+
+		case CreateTable:
+			stmts = append(stmts, change.definition.CreateStatements()...)
+			continue
+
+		case DropTable:
+			stmts = append(stmts, "DROP TABLE "+table)
+			continue
+		}
+
+		if rebuild[change.Table] {
+			if _, ok := rebuilt[change.Table]; !ok {
+				rebuilt[change.Table] = struct{}{}
+				stmts = append(stmts, rebuildStatements(change.oldDefinition, change.definition)...)
+			}
+			continue
+		}
+
+		switch change.Kind {
+		case DropIndex:
+			stmts = append(stmts, "DROP INDEX "+QuoteIdentifier(change.Index.Name))
+
+		case RenameColumn:
+			stmts = append(stmts, "ALTER TABLE "+table+" RENAME COLUMN "+QuoteIdentifier(change.OldColumn.Name)+
+				" TO "+QuoteIdentifier(change.Column.Name))
+
+		case AddColumn:
+			stmts = append(stmts, "ALTER TABLE "+table+" ADD COLUMN "+change.Column.Definition())
+
+		case DropColumn:
+			stmts = append(stmts, "ALTER TABLE "+table+" DROP COLUMN "+QuoteIdentifier(change.OldColumn.Name))
+
+		case AddIndex:
+			stmts = append(stmts, change.Index.CreateStatement(change.Table))
+		}
+	}
+
+	// Done
+	return stmts
+}
+
+// needsRebuild returns true if SQLite cannot apply the change with ALTER TABLE
+func needsRebuild(change *Change) bool {
+	switch change.Kind {
+	case RenameTable, CreateTable, DropTable, DropIndex, AddIndex, RenameColumn:
+		return false
+
+	case AddColumn:
+		// Added columns cannot be unique nor NOT NULL without a default value
+		return change.Column.Unique || (change.Column.NotNull && len(change.Column.Default) == 0)
+
+	case DropColumn:
+		// Columns that are part of a constraint cannot be dropped
+		if change.OldColumn.Unique {
+			return true
+		}
+		for _, name := range change.oldDefinition.PrimaryKey {
+			if name == change.OldColumn.Name {
+				return true
+			}
+		}
+		for _, fk := range change.oldDefinition.ForeignKeys {
+			for _, name := range fk.Columns {
+				if name == change.OldColumn.Name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// rebuildStatements replaces a table with a new one that has the new definition, copying the
+// columns that exist in both versions. The table must already have its new name.
+func rebuildStatements(oldTable *Table, newTable *Table) []string {
+	tmp := *newTable
+	tmp.Name = newTable.Name + "_new"
+	tmp.Indexes = make([]*Index, 0)
+
+	newColumns := make([]string, 0, len(newTable.Columns))
+	oldColumns := make([]string, 0, len(newTable.Columns))
+	for _, pair := range matchColumns(oldTable, newTable) {
+		if pair.old != nil && pair.new != nil {
+			newColumns = append(newColumns, QuoteIdentifier(pair.new.Name))
+			oldColumns = append(oldColumns, QuoteIdentifier(pair.old.Name))
+		}
+	}
+
+	table := QuoteIdentifier(newTable.Name)
+	stmts := tmp.CreateStatements()
+	stmts = append(stmts,
+		"INSERT INTO "+QuoteIdentifier(tmp.Name)+" ("+strings.Join(newColumns, ", ")+") SELECT "+
+			strings.Join(oldColumns, ", ")+" FROM "+table,
+		"DROP TABLE "+table,
+		"ALTER TABLE "+QuoteIdentifier(tmp.Name)+" RENAME TO "+table,
+	)
+	for _, idx := range newTable.Indexes {
+		stmts = append(stmts, idx.CreateStatement(newTable.Name))
+	}
+	return stmts
+}
+
+// constraintName returns the name PostgreSQL assigns to unnamed constraints, like users_email_key
+func constraintName(table string, columns []string, suffix string) string {
+	return QuoteIdentifier(table + "_" + strings.Join(columns, "_") + "_" + suffix)
+}
@@ -0,0 +1,518 @@
+package sqlschema
+
+import (
+	"fmt"
+	"strings"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+// DefaultTagKey is the struct tag key that contains the column definitions, for example,
+// `db:"id,pk"`. The first element is the column name and the rest are properties: pk, unique,
+// index (or index=name to group columns), default=value, default_expr=expression and
+// type=sqltype. Default values of string columns are quoted, expressions are used verbatim.
+// Properties are separated by commas or spaces, so values cannot contain them.
+const DefaultTagKey = "db"
+
+const (
+	PostgreSQL Dialect = "postgres"
+	SQLite     Dialect = "sqlite"
+)
+
+// Dialect is the SQL flavor of the generated statements
+type Dialect string
+
+type Options struct {
+	// Dialect defaults to PostgreSQL
+	Dialect Dialect
+
+	// TagKey is the struct tag key that contains the column definitions, defaults to DefaultTagKey
+	TagKey string
+
+	// TableName returns the table name of a struct, defaults to the snake_case name of the type
+	TableName func(pd *parser.ParsedDeclaration) string
+}
+
+// Table is the definition of a table created from a struct
+type Table struct {
+	Name        string
+	Struct      string // Name of the Go type, used to detect renamed tables
+	Columns     []*Column
+	PrimaryKey  []string
+	ForeignKeys []*ForeignKey
+	Indexes     []*Index
+}
+
+// Column is a column of a table
+type Column struct {
+	Name    string
+	Field   string // Selector path of the Go field, e.g. Base.ID, used to detect renamed columns
+	Type    string
+	NotNull bool
+	Unique  bool
+	Default string
+}
+
+// ForeignKey is a reference from a column to the primary key of another table
+type ForeignKey struct {
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+
+	// Deferred is set on PostgreSQL for references between tables that reference each other.
+	// These foreign keys are added with ALTER TABLE once all the tables are created.
+	Deferred bool
+}
+
+// Index is a secondary index of a table
+type Index struct {
+	Name    string
+	Columns []string
+}
+
+type generator struct {
+	opts   Options
+	decls  map[*parser.ParsedDeclaration]struct{}
+	tables map[*parser.ParsedDeclaration]*Table
+}
+
+type pendingReference struct {
+	col   *Column
+	field string
+	ref   *parser.ParsedDeclaration
+}
+
+// -----------------------------------------------------------------------------
+
+// reservedWords contains the keywords that cannot be used as identifiers without quotes in
+// PostgreSQL or SQLite
+var reservedWords = map[string]struct{}{
+	"all": {}, "analyse": {}, "analyze": {}, "and": {}, "any": {}, "array": {}, "as": {}, "asc": {},
+	"asymmetric": {}, "authorization": {}, "between": {}, "both": {}, "case": {}, "cast": {},
+	"check": {}, "collate": {}, "column": {}, "constraint": {}, "create": {}, "cross": {},
+	"current_date": {}, "current_role": {}, "current_time": {}, "current_timestamp": {},
+	"current_user": {}, "default": {}, "deferrable": {}, "delete": {}, "desc": {}, "distinct": {},
+	"do": {}, "drop": {}, "else": {}, "end": {}, "except": {}, "exists": {}, "false": {}, "fetch": {},
+	"for": {}, "foreign": {}, "from": {}, "full": {}, "grant": {}, "group": {}, "having": {}, "in": {},
+	"index": {}, "initially": {}, "inner": {}, "insert": {}, "intersect": {}, "into": {}, "is": {},
+	"join": {}, "key": {}, "lateral": {}, "leading": {}, "left": {}, "like": {}, "limit": {},
+	"localtime": {}, "localtimestamp": {}, "natural": {}, "not": {}, "null": {}, "offset": {},
+	"on": {}, "only": {}, "or": {}, "order": {}, "outer": {}, "placing": {}, "primary": {},
+	"references": {}, "returning": {}, "right": {}, "select": {}, "session_user": {}, "set": {},
+	"some": {}, "symmetric": {}, "table": {}, "then": {}, "to": {}, "trailing": {}, "true": {},
+	"union": {}, "unique": {}, "update": {}, "user": {}, "using": {}, "values": {}, "variadic": {},
+	"when": {}, "where": {}, "window": {}, "with": {},
+}
+
+// Generate returns the CREATE TABLE and CREATE INDEX statements of the given structs. Tables are
+// sorted so referenced tables are created first, foreign keys of tables that reference each other
+// are added at the end. References must be resolved before calling this function.
+func Generate(decls []*parser.ParsedDeclaration, opts Options) (string, error) {
+	tables, err := Tables(decls, opts)
+	if err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	for idx, t := range tables {
+		if idx > 0 {
+			sb.WriteString("\n")
+		}
+		for _, stmt := range t.CreateStatements() {
+			sb.WriteString(stmt + ";\n")
+		}
+	}
+
+	deferred := make([]string, 0)
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.Deferred {
+				deferred = append(deferred, fk.AddStatement(t.Name)+";\n")
+			}
+		}
+	}
+	if len(deferred) > 0 {
+		sb.WriteString("\n" + strings.Join(deferred, ""))
+	}
+
+	// Done
+	return sb.String(), nil
+}
+
+// Tables converts the given structs to table definitions sorted so referenced tables come first.
+// All the tagged structs referenced by the fields must be included.
+func Tables(decls []*parser.ParsedDeclaration, opts Options) ([]*Table, error) {
+	if len(opts.Dialect) == 0 {
+		opts.Dialect = PostgreSQL
+	}
+	if opts.Dialect != PostgreSQL && opts.Dialect != SQLite {
+		return nil, fmt.Errorf("unsupported dialect %s", opts.Dialect)
+	}
+	if len(opts.TagKey) == 0 {
+		opts.TagKey = DefaultTagKey
+	}
+	if opts.TableName == nil {
+		opts.TableName = func(pd *parser.ParsedDeclaration) string {
+			return codegen.SnakeCase(pd.Name)
+		}
+	}
+
+	g := generator{
+		opts:   opts,
+		decls:  make(map[*parser.ParsedDeclaration]struct{}),
+		tables: make(map[*parser.ParsedDeclaration]*Table),
+	}
+	for _, pd := range decls {
+		g.decls[pd] = struct{}{}
+	}
+
+	tables := make([]*Table, 0, len(decls))
+	added := make(map[*Table]struct{})
+	adding := make(map[*Table]struct{})
+	var add func(pd *parser.ParsedDeclaration) error
+	add = func(pd *parser.ParsedDeclaration) error {
+		t, err := g.table(pd)
+		if err != nil {
+			return err
+		}
+		if _, ok := added[t]; ok {
+			return nil
+		}
+		added[t] = struct{}{}
+		adding[t] = struct{}{}
+
+		// Create referenced tables first
+		for _, ref := range g.references(pd) {
+			refTable := g.tables[ref]
+			if _, ok := adding[refTable]; ok {
+				// A cycle, the referenced table is created after this one
+				if refTable != t && opts.Dialect == PostgreSQL {
+					t.deferForeignKeys(refTable.Name)
+				}
+				continue
+			}
+			if err = add(ref); err != nil {
+				return err
+			}
+		}
+
+		delete(adding, t)
+		tables = append(tables, t)
+		return nil
+	}
+	for _, pd := range decls {
+		if err := add(pd); err != nil {
+			return nil, err
+		}
+	}
+
+	// Done
+	return tables, nil
+}
+
+// CreateStatements returns the CREATE TABLE statement of the table followed by the CREATE INDEX
+// statements of its indexes, without the trailing semicolon. Deferred foreign keys are not
+// included.
+func (t *Table) CreateStatements() []string {
+	lines := make([]string, 0, len(t.Columns)+len(t.ForeignKeys)+1)
+	for _, col := range t.Columns {
+		lines = append(lines, "  "+col.Definition())
+	}
+	if len(t.PrimaryKey) > 0 {
+		lines = append(lines, "  PRIMARY KEY ("+quoteIdentifiers(t.PrimaryKey)+")")
+	}
+	for _, fk := range t.ForeignKeys {
+		if !fk.Deferred {
+			lines = append(lines, "  "+fk.Definition())
+		}
+	}
+
+	stmts := make([]string, 0, len(t.Indexes)+1)
+	stmts = append(stmts, "CREATE TABLE "+QuoteIdentifier(t.Name)+" (\n"+strings.Join(lines, ",\n")+"\n)")
+	for _, idx := range t.Indexes {
+		stmts = append(stmts, idx.CreateStatement(t.Name))
+	}
+	return stmts
+}
+
+// Definition returns the column definition used in CREATE TABLE and ALTER TABLE statements
+func (col *Column) Definition() string {
+	def := QuoteIdentifier(col.Name) + " " + col.Type
+	if col.NotNull {
+		def += " NOT NULL"
+	}
+	if col.Unique {
+		def += " UNIQUE"
+	}
+	if len(col.Default) > 0 {
+		def += " DEFAULT " + col.Default
+	}
+	return def
+}
+
+// Definition returns the table constraint of the foreign key
+func (fk *ForeignKey) Definition() string {
+	return "FOREIGN KEY (" + quoteIdentifiers(fk.Columns) + ") REFERENCES " + QuoteIdentifier(fk.RefTable) +
+		" (" + quoteIdentifiers(fk.RefColumns) + ")"
+}
+
+// AddStatement returns the ALTER TABLE statement that adds the foreign key to the table
+func (fk *ForeignKey) AddStatement(table string) string {
+	return "ALTER TABLE " + QuoteIdentifier(table) + " ADD CONSTRAINT " + constraintName(table, fk.Columns, "fkey") +
+		" " + fk.Definition()
+}
+
+// CreateStatement returns the CREATE INDEX statement of the index
+func (idx *Index) CreateStatement(table string) string {
+	return "CREATE INDEX " + QuoteIdentifier(idx.Name) + " ON " + QuoteIdentifier(table) + " (" +
+		quoteIdentifiers(idx.Columns) + ")"
+}
+
+// QuoteIdentifier quotes a table, column or index name if it is a reserved word or contains
+// characters other than lowercase letters, digits and underscores
+func QuoteIdentifier(name string) string {
+	if _, reserved := reservedWords[name]; !reserved && len(name) > 0 && !(name[0] >= '0' && name[0] <= '9') {
+		simple := true
+		for _, ch := range name {
+			if !(ch >= 'a' && ch <= 'z') && !(ch >= '0' && ch <= '9') && ch != '_' {
+				simple = false
+				break
+			}
+		}
+		if simple {
+			return name
+		}
+	}
+	return "\"" + strings.ReplaceAll(name, "\"", "\"\"") + "\""
+}
+
+// -----------------------------------------------------------------------------
+
+func (g *generator) table(pd *parser.ParsedDeclaration) (*Table, error) {
+	if t, ok := g.tables[pd]; ok {
+		return t, nil
+	}
+
+	ps, ok := pd.Underlying().(*parser.ParsedStruct)
+	if !ok {
+		return nil, fmt.Errorf("declaration %s is not a struct", pd.Name)
+	}
+	if len(pd.TypeParams) > 0 {
+		return nil, fmt.Errorf("generic declaration %s must be instantiated", pd.Name)
+	}
+
+	t := &Table{
+		Name:        g.opts.TableName(pd),
+		Struct:      pd.Name,
+		Columns:     make([]*Column, 0),
+		PrimaryKey:  make([]string, 0),
+		ForeignKeys: make([]*ForeignKey, 0),
+		Indexes:     make([]*Index, 0),
+	}
+	g.tables[pd] = t
+
+	refs := make([]pendingReference, 0)
+	for _, ff := range ps.FlattenedFields() {
+		tag, ok := ff.Field.Tags.GetTag(g.opts.TagKey)
+		if !ok || tag == "-" {
+			continue
+		}
+		name, props := codegen.SplitTag(tag)
+		if len(name) == 0 {
+			name = codegen.SnakeCase(ff.Name)
+		}
+
+		col := &Column{
+			Name:  name,
+			Field: strings.Join(ff.Path, "."),
+		}
+
+		// References to other tagged structs are foreign keys to their primary key
+		fieldType := parser.Unalias(ff.Field.Type)
+		nullable := false
+		if pp, isPointer := fieldType.(*parser.ParsedPointer); isPointer {
+			nullable = true
+			fieldType = parser.Unalias(pp.ToType)
+		}
+		if ref := g.taggedStruct(fieldType); ref != nil {
+			refs = append(refs, pendingReference{
+				col:   col,
+				field: ff.Name,
+				ref:   ref,
+			})
+		} else {
+			var err error
+
+			col.Type, nullable, err = columnType(ff.Field.Type, g.opts.Dialect)
+			if err != nil {
+				return nil, fmt.Errorf("unable to convert field %s of %s [err=%v]", ff.Name, pd.Name, err)
+			}
+		}
+		col.NotNull = !nullable
+
+		if sqlType, found := props.GetProperty("type"); found && len(sqlType) > 0 {
+			col.Type = sqlType
+		}
+		if props.HasProperty("pk") {
+			t.PrimaryKey = append(t.PrimaryKey, name)
+			col.NotNull = true
+		}
+		if props.HasProperty("unique") {
+			col.Unique = true
+		}
+		if value, found := props.GetProperty("default"); found && len(value) > 0 {
+			col.Default = defaultValue(value, ff.Field.Type)
+		}
+		if expr, found := props.GetProperty("default_expr"); found && len(expr) > 0 {
+			if len(col.Default) > 0 {
+				return nil, fmt.Errorf("field %s of %s cannot have both default and default_expr", ff.Name, pd.Name)
+			}
+			col.Default = expr
+		}
+		if indexName, found := props.GetProperty("index"); found {
+			t.addIndex(indexName, name)
+		}
+
+		t.Columns = append(t.Columns, col)
+	}
+	if len(t.Columns) == 0 {
+		return nil, fmt.Errorf("struct %s does not have fields with the %s tag", pd.Name, g.opts.TagKey)
+	}
+
+	// Foreign keys are added once the primary key is known, so a struct can reference itself
+	for _, pr := range refs {
+		if _, ok := g.decls[pr.ref]; !ok {
+			return nil, fmt.Errorf("field %s of %s references %s, which is not one of the converted structs",
+				pr.field, pd.Name, pr.ref.Name)
+		}
+		refTable, err := g.table(pr.ref)
+		if err != nil {
+			return nil, err
+		}
+		refCol, err := refTable.singlePrimaryKey()
+		if err != nil {
+			return nil, fmt.Errorf("field %s of %s cannot reference %s [err=%v]", pr.field, pd.Name, pr.ref.Name, err)
+		}
+		if len(pr.col.Type) == 0 {
+			pr.col.Type = referenceType(refCol.Type)
+		}
+		t.ForeignKeys = append(t.ForeignKeys, &ForeignKey{
+			Columns:    []string{pr.col.Name},
+			RefTable:   refTable.Name,
+			RefColumns: []string{refCol.Name},
+		})
+	}
+
+	// Done
+	return t, nil
+}
+
+// taggedStruct returns the declaration of a named struct that has at least one field with the tag
+func (g *generator) taggedStruct(t parser.ParsedType) *parser.ParsedDeclaration {
+	pnnt, ok := t.(*parser.ParsedNonNativeType)
+	if !ok || pnnt.Ref == nil || len(pnnt.Ref.TypeParams) > 0 {
+		return nil
+	}
+	ps, ok := pnnt.Ref.Underlying().(*parser.ParsedStruct)
+	if !ok {
+		return nil
+	}
+	for _, ff := range ps.FlattenedFields() {
+		if tag, found := ff.Field.Tags.GetTag(g.opts.TagKey); found && tag != "-" {
+			return pnnt.Ref
+		}
+	}
+	return nil
+}
+
+// references returns the tagged structs referenced by the fields of a struct
+func (g *generator) references(pd *parser.ParsedDeclaration) []*parser.ParsedDeclaration {
+	refs := make([]*parser.ParsedDeclaration, 0)
+	ps, _ := pd.Underlying().(*parser.ParsedStruct)
+	if ps == nil {
+		return refs
+	}
+	for _, ff := range ps.FlattenedFields() {
+		if tag, found := ff.Field.Tags.GetTag(g.opts.TagKey); !found || tag == "-" {
+			continue
+		}
+		t := parser.Unalias(ff.Field.Type)
+		if pp, ok := t.(*parser.ParsedPointer); ok {
+			t = parser.Unalias(pp.ToType)
+		}
+		if ref := g.taggedStruct(t); ref != nil {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func (t *Table) addIndex(name string, column string) {
+	if len(name) == 0 {
+		name = "idx_" + t.Name + "_" + column
+	}
+	for _, idx := range t.Indexes {
+		if idx.Name == name {
+			idx.Columns = append(idx.Columns, column)
+			return
+		}
+	}
+	t.Indexes = append(t.Indexes, &Index{
+		Name:    name,
+		Columns: []string{column},
+	})
+}
+
+// deferForeignKeys marks the foreign keys that reference the given table as deferred
+func (t *Table) deferForeignKeys(refTable string) {
+	for _, fk := range t.ForeignKeys {
+		if fk.RefTable == refTable {
+			fk.Deferred = true
+		}
+	}
+}
+
+func (t *Table) singlePrimaryKey() (*Column, error) {
+	if len(t.PrimaryKey) != 1 {
+		return nil, fmt.Errorf("table %s must have a single column primary key", t.Name)
+	}
+	for _, col := range t.Columns {
+		if col.Name == t.PrimaryKey[0] {
+			return col, nil
+		}
+	}
+	return nil, fmt.Errorf("primary key column %s of table %s not found", t.PrimaryKey[0], t.Name)
+}
+
+// -----------------------------------------------------------------------------
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, QuoteIdentifier(name))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// defaultValue quotes default values of string columns
+func defaultValue(value string, t parser.ParsedType) string {
+	if parser.IsString(t) {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return value
+}
+
+// referenceType returns the type of a column that references a primary key, which cannot be an
+// auto-incremented type
+func referenceType(sqlType string) string {
+	switch strings.ToUpper(sqlType) {
+	case "SERIAL":
+		return "INTEGER"
+	case "BIGSERIAL":
+		return "BIGINT"
+	}
+	return sqlType
+}
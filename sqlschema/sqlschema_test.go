@@ -0,0 +1,485 @@
+package sqlschema_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/sqlschema"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+import (
+	"time"
+)
+
+type Status string
+
+type Order struct {
+	ID       int64     ` + "`db:\"id,pk\"`" + `
+	Customer *Customer ` + "`db:\"customer_id,index\"`" + `
+	Status   Status    ` + "`db:\"status,default=pending\"`" + `
+	Total    float64   ` + "`db:\"total\"`" + `
+	Note     *string   ` + "`db:\"note\"`" + `
+	Tags     []string  ` + "`db:\"tags\"`" + `
+	Created  time.Time ` + "`db:\"created_at,default_expr=now(),index=idx_order_created\"`" + `
+	Cached   string    ` + "`db:\"-\"`" + `
+	Other    string
+}
+
+type Customer struct {
+	ID    int64  ` + "`db:\"id,pk\"`" + `
+	Email string ` + "`db:\"email,unique\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	decls := []*parser.ParsedDeclaration{&pf.Declarations[1], &pf.Declarations[2]}
+
+	ddl, err := sqlschema.Generate(decls, sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate tables [err=%v]", err)
+	}
+	expected := `CREATE TABLE customer (
+  id BIGINT NOT NULL,
+  email TEXT NOT NULL UNIQUE,
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE "order" (
+  id BIGINT NOT NULL,
+  customer_id BIGINT,
+  status TEXT NOT NULL DEFAULT 'pending',
+  total DOUBLE PRECISION NOT NULL,
+  note TEXT,
+  tags JSONB NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (id),
+  FOREIGN KEY (customer_id) REFERENCES customer (id)
+);
+CREATE INDEX idx_order_customer_id ON "order" (customer_id);
+CREATE INDEX idx_order_created ON "order" (created_at);
+`
+	if ddl != expected {
+		t.Fatalf("wrong postgres statements:\n%v", ddl)
+	}
+
+	tables, err := sqlschema.Tables(decls, sqlschema.Options{
+		Dialect: sqlschema.SQLite,
+		TableName: func(pd *parser.ParsedDeclaration) string {
+			return "tbl_" + pd.Name
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to generate tables [err=%v]", err)
+	}
+	if tables[1].Name != "tbl_Order" || tables[1].Columns[0].Type != "INTEGER" || tables[1].Columns[6].Type != "DATETIME" {
+		t.Fatalf("wrong sqlite tables")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	v1, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Customer struct {
+	ID    int64  ` + "`db:\"id,pk\"`" + `
+	Email string ` + "`db:\"email\"`" + `
+	Name  string ` + "`db:\"name\"`" + `
+}
+
+type Order struct {
+	ID       int64     ` + "`db:\"id,pk\"`" + `
+	Customer *Customer ` + "`db:\"customer_id\"`" + `
+	Total    float32   ` + "`db:\"total\"`" + `
+	Note     string    ` + "`db:\"note\"`" + `
+	Legacy   string    ` + "`db:\"legacy\"`" + `
+}
+
+type Audit struct {
+	ID int64 ` + "`db:\"id,pk\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	// Use a JSON snapshot of the old version
+	ps := parser.NewPackageSet([]*parser.ParsedFile{v1})
+	ps.ResolveReferences()
+	data, err := json.Marshal(ps)
+	if err != nil {
+		t.Fatalf("unable to encode snapshot [err=%v]", err)
+	}
+	snapshot := &parser.PackageSet{}
+	if err = json.Unmarshal(data, snapshot); err != nil {
+		t.Fatalf("unable to decode snapshot [err=%v]", err)
+	}
+
+	v2, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Customer struct {
+	ID    int64  ` + "`db:\"id,pk\"`" + `
+	Email string ` + "`db:\"email,unique,index\"`" + `
+	Name  string ` + "`db:\"full_name\"`" + `
+}
+
+type Order struct {
+	ID       int64     ` + "`db:\"id,pk\"`" + `
+	Customer *Customer ` + "`db:\"customer_id\"`" + `
+	Total    float64   ` + "`db:\"total\"`" + `
+	Note     *string   ` + "`db:\"note\"`" + `
+	Status   string    ` + "`db:\"status,default=new\"`" + `
+}
+
+type Invoice struct {
+	ID    int64 ` + "`db:\"id,pk\"`" + `
+	Order Order ` + "`db:\"order_id\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{v2})
+
+	oldDecls := sqlschema.TaggedStructs(snapshot.Packages["github.com/mxmauro/gofile-parser-test"], "")
+	newDecls := sqlschema.TaggedStructs([]*parser.ParsedFile{v2}, "")
+
+	script, err := sqlschema.Migrate(oldDecls, newDecls, sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate migration [err=%v]", err)
+	}
+	expected := `ALTER TABLE customer RENAME COLUMN name TO full_name;
+CREATE TABLE invoice (
+  id BIGINT NOT NULL,
+  order_id BIGINT NOT NULL,
+  PRIMARY KEY (id),
+  FOREIGN KEY (order_id) REFERENCES "order" (id)
+);
+ALTER TABLE "order" ADD COLUMN status TEXT NOT NULL DEFAULT 'new';
+ALTER TABLE "order" ALTER COLUMN total TYPE DOUBLE PRECISION USING total::DOUBLE PRECISION;
+ALTER TABLE "order" ALTER COLUMN note DROP NOT NULL;
+ALTER TABLE customer ADD CONSTRAINT customer_email_key UNIQUE (email);
+ALTER TABLE "order" DROP COLUMN legacy;
+CREATE INDEX idx_customer_email ON customer (email);
+DROP TABLE audit;
+`
+	if script != expected {
+		t.Fatalf("wrong postgres migration:\n%v", script)
+	}
+
+	script, err = sqlschema.Migrate(oldDecls, newDecls, sqlschema.Options{
+		Dialect: sqlschema.SQLite,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate migration [err=%v]", err)
+	}
+	expected = `CREATE TABLE customer_new (
+  id INTEGER NOT NULL,
+  email TEXT NOT NULL UNIQUE,
+  full_name TEXT NOT NULL,
+  PRIMARY KEY (id)
+);
+INSERT INTO customer_new (id, email, full_name) SELECT id, email, name FROM customer;
+DROP TABLE customer;
+ALTER TABLE customer_new RENAME TO customer;
+CREATE INDEX idx_customer_email ON customer (email);
+CREATE TABLE invoice (
+  id INTEGER NOT NULL,
+  order_id INTEGER NOT NULL,
+  PRIMARY KEY (id),
+  FOREIGN KEY (order_id) REFERENCES "order" (id)
+);
+CREATE TABLE order_new (
+  id INTEGER NOT NULL,
+  customer_id INTEGER,
+  total REAL NOT NULL,
+  note TEXT,
+  status TEXT NOT NULL DEFAULT 'new',
+  PRIMARY KEY (id),
+  FOREIGN KEY (customer_id) REFERENCES customer (id)
+);
+INSERT INTO order_new (id, customer_id, total, note) SELECT id, customer_id, total, note FROM "order";
+DROP TABLE "order";
+ALTER TABLE order_new RENAME TO "order";
+DROP TABLE audit;
+`
+	if script != expected {
+		t.Fatalf("wrong sqlite migration:\n%v", script)
+	}
+}
+
+func TestSelfReference(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Category struct {
+	Parent *Category ` + "`db:\"parent_id\"`" + `
+	ID     int64     ` + "`db:\"id,pk,type=BIGSERIAL\"`" + `
+	Name   string    ` + "`db:\"name\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	ddl, err := sqlschema.Generate([]*parser.ParsedDeclaration{&pf.Declarations[0]}, sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate tables [err=%v]", err)
+	}
+	expected := `CREATE TABLE category (
+  parent_id BIGINT,
+  id BIGSERIAL NOT NULL,
+  name TEXT NOT NULL,
+  PRIMARY KEY (id),
+  FOREIGN KEY (parent_id) REFERENCES category (id)
+);
+`
+	if ddl != expected {
+		t.Fatalf("wrong postgres statements:\n%v", ddl)
+	}
+}
+
+func TestMutualReferences(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Team struct {
+	ID    int64 ` + "`db:\"id,pk\"`" + `
+	Owner *User ` + "`db:\"owner_id\"`" + `
+}
+
+type User struct {
+	ID   int64 ` + "`db:\"id,pk\"`" + `
+	Team Team  ` + "`db:\"team_id\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	decls := []*parser.ParsedDeclaration{&pf.Declarations[0], &pf.Declarations[1]}
+
+	// The foreign key of the table created first is added once both tables exist
+	ddl, err := sqlschema.Generate(decls, sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate tables [err=%v]", err)
+	}
+	expected := `CREATE TABLE "user" (
+  id BIGINT NOT NULL,
+  team_id BIGINT NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE team (
+  id BIGINT NOT NULL,
+  owner_id BIGINT,
+  PRIMARY KEY (id),
+  FOREIGN KEY (owner_id) REFERENCES "user" (id)
+);
+
+ALTER TABLE "user" ADD CONSTRAINT user_team_id_fkey FOREIGN KEY (team_id) REFERENCES team (id);
+`
+	if ddl != expected {
+		t.Fatalf("wrong postgres statements:\n%v", ddl)
+	}
+
+	script, err := sqlschema.Migrate(nil, decls, sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate migration [err=%v]", err)
+	}
+	if script != strings.ReplaceAll(ddl, "\n\n", "\n") {
+		t.Fatalf("wrong postgres migration:\n%v", script)
+	}
+
+	// SQLite accepts references to tables that do not exist yet
+	ddl, err = sqlschema.Generate(decls, sqlschema.Options{
+		Dialect: sqlschema.SQLite,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate tables [err=%v]", err)
+	}
+	if strings.Contains(ddl, "ALTER TABLE") || !strings.Contains(ddl, "FOREIGN KEY (team_id) REFERENCES team (id)") {
+		t.Fatalf("wrong sqlite statements:\n%v", ddl)
+	}
+
+	// Referenced tables must be created too
+	_, err = sqlschema.Generate(decls[:1], sqlschema.Options{})
+	if err == nil || err.Error() != "field Owner of Team references User, which is not one of the converted structs" {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		field    string
+		expected string
+	}{
+		{"Status string `db:\"status,default=pending\"`", "status TEXT NOT NULL DEFAULT 'pending'"},
+		{"Status string `db:\"status,default=it's\"`", "status TEXT NOT NULL DEFAULT 'it''s'"},
+		{"Status string `db:\"status,default=upper()\"`", "status TEXT NOT NULL DEFAULT 'upper()'"},
+		{"Status string `db:\"status,default_expr=current_user\"`", "status TEXT NOT NULL DEFAULT current_user"},
+		{"Count int `db:\"count,default=5\"`", "count BIGINT NOT NULL DEFAULT 5"},
+		{"Status string `db:\"status,default=a,default_expr=b\"`", "field Status of Order cannot have both default and default_expr"},
+	} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content:  "package main\n\ntype Order struct {\n\t" + tc.field + "\n}\n",
+			Filename: "test.go",
+			Module: parser.Module{
+				Name: "github.com/mxmauro/gofile-parser-test",
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+
+		tables, err := sqlschema.Tables([]*parser.ParsedDeclaration{&pf.Declarations[0]}, sqlschema.Options{})
+		if err != nil {
+			if err.Error() != tc.expected {
+				t.Fatalf("wrong error for %v: %v", tc.field, err)
+			}
+		} else if tables[0].Columns[0].Definition() != tc.expected {
+			t.Fatalf("wrong definition for %v: %v", tc.field, tables[0].Columns[0].Definition())
+		}
+	}
+}
+
+func TestMigrateSelfReference(t *testing.T) {
+	versions := make([][]*parser.ParsedDeclaration, 0, 2)
+	for _, content := range []string{`
+package main
+
+type Category struct {
+	ID   int64  ` + "`db:\"id,pk\"`" + `
+	Name string ` + "`db:\"name\"`" + `
+}
+`, `
+package main
+
+type Category struct {
+	ID     int64     ` + "`db:\"id,pk\"`" + `
+	Name   string    ` + "`db:\"name\"`" + `
+	Parent *Category ` + "`db:\"parent_id\"`" + `
+}
+`} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content:  content,
+			Filename: "test.go",
+			Module: parser.Module{
+				Name: "github.com/mxmauro/gofile-parser-test",
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		parser.ResolveReferences([]*parser.ParsedFile{pf})
+		versions = append(versions, sqlschema.TaggedStructs([]*parser.ParsedFile{pf}, ""))
+	}
+
+	script, err := sqlschema.Migrate(versions[0], versions[1], sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate migration [err=%v]", err)
+	}
+	expected := `ALTER TABLE category ADD COLUMN parent_id BIGINT;
+ALTER TABLE category ADD CONSTRAINT category_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES category (id);
+`
+	if script != expected {
+		t.Fatalf("wrong postgres migration:\n%v", script)
+	}
+
+	script, err = sqlschema.Migrate(versions[1], versions[0], sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate migration [err=%v]", err)
+	}
+	expected = `ALTER TABLE category DROP CONSTRAINT category_parent_id_fkey;
+ALTER TABLE category DROP COLUMN parent_id;
+`
+	if script != expected {
+		t.Fatalf("wrong postgres migration:\n%v", script)
+	}
+}
+
+func TestMigrateNotNullColumn(t *testing.T) {
+	versions := make([][]*parser.ParsedDeclaration, 0, 3)
+	for _, field := range []string{
+		"",
+		"Email string `db:\"email\"`",
+		"Email string `db:\"email,default=none\"`",
+	} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content:  "package main\n\ntype Account struct {\n\tID int64 `db:\"id,pk\"`\n\t" + field + "\n}\n",
+			Filename: "test.go",
+			Module: parser.Module{
+				Name: "github.com/mxmauro/gofile-parser-test",
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		versions = append(versions, []*parser.ParsedDeclaration{&pf.Declarations[0]})
+	}
+
+	// Existing rows need a value for the new column, in SQLite they would be copied without it
+	for _, dialect := range []sqlschema.Dialect{sqlschema.PostgreSQL, sqlschema.SQLite} {
+		_, err := sqlschema.Migrate(versions[0], versions[1], sqlschema.Options{
+			Dialect: dialect,
+		})
+		if err == nil || err.Error() != "column email added to table account is NOT NULL and must have a default value" {
+			t.Fatalf("wrong error for %v: %v", dialect, err)
+		}
+	}
+
+	script, err := sqlschema.Migrate(versions[0], versions[2], sqlschema.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate migration [err=%v]", err)
+	}
+	expected := `ALTER TABLE account ADD COLUMN email TEXT NOT NULL DEFAULT 'none';
+`
+	if script != expected {
+		t.Fatalf("wrong postgres migration:\n%v", script)
+	}
+}
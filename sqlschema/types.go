@@ -0,0 +1,117 @@
+package sqlschema
+
+import (
+	"fmt"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+type sqlType struct {
+	postgres string
+	sqlite   string
+	nullable bool
+}
+
+// -----------------------------------------------------------------------------
+
+var wellKnownTypes = map[string]sqlType{
+	"time.Time":                       {"TIMESTAMP WITH TIME ZONE", "DATETIME", false},
+	"time.Duration":                   {"BIGINT", "INTEGER", false},
+	"encoding/json.RawMessage":        {"JSONB", "TEXT", false},
+	"github.com/google/uuid.UUID":     {"UUID", "TEXT", false},
+	"database/sql.NullString":         {"TEXT", "TEXT", true},
+	"database/sql.NullBool":           {"BOOLEAN", "INTEGER", true},
+	"database/sql.NullByte":           {"SMALLINT", "INTEGER", true},
+	"database/sql.NullInt16":          {"SMALLINT", "INTEGER", true},
+	"database/sql.NullInt32":          {"INTEGER", "INTEGER", true},
+	"database/sql.NullInt64":          {"BIGINT", "INTEGER", true},
+	"database/sql.NullFloat64":        {"DOUBLE PRECISION", "REAL", true},
+	"database/sql.NullTime":           {"TIMESTAMP WITH TIME ZONE", "DATETIME", true},
+	"github.com/google/uuid.NullUUID": {"UUID", "TEXT", true},
+}
+
+var nativeTypes = map[string]sqlType{
+	"bool":    {"BOOLEAN", "INTEGER", false},
+	"string":  {"TEXT", "TEXT", false},
+	"int8":    {"SMALLINT", "INTEGER", false},
+	"int16":   {"SMALLINT", "INTEGER", false},
+	"uint8":   {"SMALLINT", "INTEGER", false},
+	"byte":    {"SMALLINT", "INTEGER", false},
+	"int32":   {"INTEGER", "INTEGER", false},
+	"rune":    {"INTEGER", "INTEGER", false},
+	"uint16":  {"INTEGER", "INTEGER", false},
+	"int":     {"BIGINT", "INTEGER", false},
+	"int64":   {"BIGINT", "INTEGER", false},
+	"uint32":  {"BIGINT", "INTEGER", false},
+	"uint":    {"BIGINT", "INTEGER", false},
+	"uint64":  {"BIGINT", "INTEGER", false},
+	"float32": {"REAL", "REAL", false},
+	"float64": {"DOUBLE PRECISION", "REAL", false},
+}
+
+// columnType returns the SQL type of a field and if the column is nullable. Pointers and the
+// nullable types of database/sql are nullable. Slices, maps and structs are stored as JSON.
+func columnType(t parser.ParsedType, dialect Dialect) (string, bool, error) {
+	st, err := resolveType(t, make(map[*parser.ParsedDeclaration]struct{}))
+	if err != nil {
+		return "", false, err
+	}
+	if dialect == SQLite {
+		return st.sqlite, st.nullable, nil
+	}
+	return st.postgres, st.nullable, nil
+}
+
+func resolveType(t parser.ParsedType, visited map[*parser.ParsedDeclaration]struct{}) (sqlType, error) {
+	switch tType := t.(type) {
+	case *parser.ParsedNativeType:
+		if st, ok := nativeTypes[tType.Name]; ok {
+			return st, nil
+		}
+
+	case *parser.ParsedNonNativeType:
+		if st, ok := wellKnownTypes[codegen.ID(tType)]; ok {
+			return st, nil
+		}
+		if tType.Ref == nil {
+			return sqlType{}, fmt.Errorf("unresolved reference to %s", tType.Name)
+		}
+
+		pd := tType.Ref
+		if _, ok := visited[pd]; ok {
+			return sqlType{}, fmt.Errorf("invalid recursive type %s", pd.Name)
+		}
+		visited[pd] = struct{}{}
+		defer delete(visited, pd)
+		return resolveType(pd.Type, visited)
+
+	case *parser.ParsedIndex:
+		pd, err := parser.Instantiate(tType)
+		if err != nil {
+			return sqlType{}, fmt.Errorf("unable to instantiate %s [err=%v]", parser.Render(tType, nil), err)
+		}
+		return resolveType(pd.Type, visited)
+
+	case *parser.ParsedPointer:
+		st, err := resolveType(tType.ToType, visited)
+		if err != nil {
+			return sqlType{}, err
+		}
+		st.nullable = true
+		return st, nil
+
+	case *parser.ParsedArray:
+		if len(tType.Size) == 0 && codegen.IsByte(tType.ValueType) {
+			return sqlType{"BYTEA", "BLOB", false}, nil
+		}
+		return sqlType{"JSONB", "TEXT", false}, nil
+
+	case *parser.ParsedMap, *parser.ParsedStruct:
+		return sqlType{"JSONB", "TEXT", false}, nil
+	}
+
+	return sqlType{}, fmt.Errorf("type %s cannot be stored in a column", parser.Render(t, nil))
+}
@@ -0,0 +1,161 @@
+package parser
+
+// -----------------------------------------------------------------------------
+
+type recursionAnalyzer struct {
+	index   int
+	indexes map[*ParsedDeclaration]int
+	lowLink map[*ParsedDeclaration]int
+	onStack map[*ParsedDeclaration]bool
+	stack   []*ParsedDeclaration
+	groups  map[*ParsedDeclaration][]*ParsedDeclaration
+}
+
+// -----------------------------------------------------------------------------
+
+// References returns the declarations directly referenced by the given type, in order of
+// appearance and without duplicates. Only resolved references are returned.
+func References(t ParsedType) []*ParsedDeclaration {
+	refs := make([]*ParsedDeclaration, 0)
+	seen := make(map[*ParsedDeclaration]struct{})
+
+	forEachReference(t, func(pnnt *ParsedNonNativeType) {
+		if pnnt.Ref != nil {
+			if _, ok := seen[pnnt.Ref]; !ok {
+				seen[pnnt.Ref] = struct{}{}
+				refs = append(refs, pnnt.Ref)
+			}
+		}
+	})
+
+	// Done
+	return refs
+}
+
+// WalkDeclarations calls fn for the given declaration and every declaration reachable from it
+// through resolved references. Each declaration is visited once, so self-referential types do
+// not loop forever. If fn returns false, the references of that declaration are not followed.
+func WalkDeclarations(pd *ParsedDeclaration, fn func(pd *ParsedDeclaration) bool) {
+	visited := make(map[*ParsedDeclaration]struct{})
+
+	var walk func(pd *ParsedDeclaration)
+	walk = func(pd *ParsedDeclaration) {
+		if _, ok := visited[pd]; ok {
+			return
+		}
+		visited[pd] = struct{}{}
+
+		if fn(pd) {
+			for _, ref := range References(pd.Type) {
+				walk(ref)
+			}
+		}
+	}
+	walk(pd)
+}
+
+// FindRecursiveDeclarations returns the declarations that reference themselves, directly or
+// through other declarations. Each recursive declaration is mapped to the group of mutually
+// recursive declarations it belongs to.
+func FindRecursiveDeclarations(parsedFiles []*ParsedFile) map[*ParsedDeclaration][]*ParsedDeclaration {
+	ra := recursionAnalyzer{
+		indexes: make(map[*ParsedDeclaration]int),
+		lowLink: make(map[*ParsedDeclaration]int),
+		onStack: make(map[*ParsedDeclaration]bool),
+		stack:   make([]*ParsedDeclaration, 0),
+		groups:  make(map[*ParsedDeclaration][]*ParsedDeclaration),
+	}
+
+	for _, pf := range parsedFiles {
+		for pdIdx := range pf.Declarations {
+			pd := &pf.Declarations[pdIdx]
+			if _, ok := ra.indexes[pd]; !ok {
+				ra.strongConnect(pd)
+			}
+		}
+	}
+
+	// Done
+	return ra.groups
+}
+
+// IsRecursive returns true if the declaration references itself, directly or through other
+// declarations.
+func IsRecursive(pd *ParsedDeclaration) bool {
+	found := false
+	WalkDeclarations(pd, func(visited *ParsedDeclaration) bool {
+		if !found {
+			for _, ref := range References(visited.Type) {
+				if ref == pd {
+					found = true
+					break
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// strongConnect implements Tarjan's strongly connected components algorithm
+func (ra *recursionAnalyzer) strongConnect(pd *ParsedDeclaration) {
+	ra.indexes[pd] = ra.index
+	ra.lowLink[pd] = ra.index
+	ra.index += 1
+	ra.stack = append(ra.stack, pd)
+	ra.onStack[pd] = true
+
+	selfReference := false
+	for _, ref := range References(pd.Type) {
+		if ref == pd {
+			selfReference = true
+		}
+		if _, ok := ra.indexes[ref]; !ok {
+			ra.strongConnect(ref)
+			if ra.lowLink[ref] < ra.lowLink[pd] {
+				ra.lowLink[pd] = ra.lowLink[ref]
+			}
+		} else if ra.onStack[ref] {
+			if ra.indexes[ref] < ra.lowLink[pd] {
+				ra.lowLink[pd] = ra.indexes[ref]
+			}
+		}
+	}
+
+	if ra.lowLink[pd] != ra.indexes[pd] {
+		return
+	}
+
+	// Pop the component
+	group := make([]*ParsedDeclaration, 0)
+	for {
+		top := ra.stack[len(ra.stack)-1]
+		ra.stack = ra.stack[:len(ra.stack)-1]
+		ra.onStack[top] = false
+		group = append(group, top)
+		if top == pd {
+			break
+		}
+	}
+
+	// A single declaration is only recursive if it references itself
+	if len(group) > 1 || selfReference {
+		for _, member := range group {
+			ra.groups[member] = group
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func forEachReference(t ParsedType, fn func(pnnt *ParsedNonNativeType)) {
+	if t == nil {
+		return
+	}
+	Walk(t, func(node interface{}, _ Path) bool {
+		if pnnt, ok := node.(*ParsedNonNativeType); ok {
+			fn(pnnt)
+		}
+		return true
+	})
+}
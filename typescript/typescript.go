@@ -0,0 +1,520 @@
+package typescript
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"sort"
+	"strings"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/internal/codegen"
+)
+
+// -----------------------------------------------------------------------------
+
+type Options struct {
+	// Packages, if set, is used to find the constants declared with a named type, which are
+	// emitted as a union of literals.
+	Packages *parser.PackageSet
+
+	// Filename returns the name of the .d.ts file of a Go package. It defaults to
+	// `<import path>/<package name>.d.ts`. Imports between files use relative paths.
+	Filename func(importPath string, packageName string) string
+}
+
+// File is the TypeScript declaration file of a Go package
+type File struct {
+	ImportPath   string
+	Package      string
+	Filename     string
+	Imports      []Import
+	Declarations []Declaration
+}
+
+// Import is a namespace import of the declarations of another package, like
+// `import type * as common from "../common/common";`
+type Import struct {
+	Alias      string
+	From       string
+	importPath string
+}
+
+// Declaration is an exported interface or type alias
+type Declaration struct {
+	Name string
+	Code string
+}
+
+type generator struct {
+	opts   Options
+	files  []*File
+	byPath map[string]*File
+	queued map[*parser.ParsedDeclaration]struct{}
+	queue  []*parser.ParsedDeclaration
+}
+
+// -----------------------------------------------------------------------------
+
+var wellKnownTypes = map[string]string{
+	"time.Time":                   "string",
+	"time.Duration":               "number",
+	"encoding/json.RawMessage":    "unknown",
+	"net/url.URL":                 "string",
+	"github.com/google/uuid.UUID": "string",
+}
+
+// Generate converts the given declarations, and the ones they reference, to TypeScript
+// declarations following the encoding/json rules. One file is returned for each Go package.
+// References must be resolved before calling this function.
+func Generate(decls []*parser.ParsedDeclaration, opts Options) ([]*File, error) {
+	if opts.Filename == nil {
+		opts.Filename = func(importPath string, packageName string) string {
+			return importPath + "/" + packageName + ".d.ts"
+		}
+	}
+
+	g := generator{
+		opts:   opts,
+		files:  make([]*File, 0),
+		byPath: make(map[string]*File),
+		queued: make(map[*parser.ParsedDeclaration]struct{}),
+		queue:  make([]*parser.ParsedDeclaration, 0),
+	}
+
+	for _, pd := range decls {
+		if _, err := g.enqueue(pd); err != nil {
+			return nil, err
+		}
+	}
+	for len(g.queue) > 0 {
+		pd := g.queue[0]
+		g.queue = g.queue[1:]
+
+		err := g.declaration(pd)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert %s [err=%v]", pd.Name, err)
+		}
+	}
+
+	for _, f := range g.files {
+		sort.Slice(f.Imports, func(i, j int) bool {
+			return f.Imports[i].From < f.Imports[j].From
+		})
+	}
+
+	// Done
+	return g.files, nil
+}
+
+// String returns the content of the .d.ts file
+func (f *File) String() string {
+	sb := strings.Builder{}
+
+	for _, imp := range f.Imports {
+		sb.WriteString("import type * as " + imp.Alias + " from " + quote(imp.From) + ";\n")
+	}
+	for idx, decl := range f.Declarations {
+		if idx > 0 || len(f.Imports) > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(decl.Code)
+	}
+
+	// Done
+	return sb.String()
+}
+
+// -----------------------------------------------------------------------------
+
+// enqueue schedules the conversion of a declaration and returns the file where it will be stored
+func (g *generator) enqueue(pd *parser.ParsedDeclaration) (*File, error) {
+	pf := pd.File()
+	if pf == nil {
+		return nil, fmt.Errorf("declaration %s does not belong to a parsed file", pd.Name)
+	}
+	importPath := pf.Module.FullName()
+
+	f, ok := g.byPath[importPath]
+	if !ok {
+		f = &File{
+			ImportPath:   importPath,
+			Package:      pf.Package,
+			Filename:     g.opts.Filename(importPath, pf.Package),
+			Imports:      make([]Import, 0),
+			Declarations: make([]Declaration, 0),
+		}
+		g.byPath[importPath] = f
+		g.files = append(g.files, f)
+	}
+
+	if _, ok = g.queued[pd]; !ok {
+		g.queued[pd] = struct{}{}
+		g.queue = append(g.queue, pd)
+	}
+	return f, nil
+}
+
+func (g *generator) declaration(pd *parser.ParsedDeclaration) error {
+	f := g.byPath[pd.File().Module.FullName()]
+
+	sb := strings.Builder{}
+	writeDoc(&sb, pd.Doc, "")
+
+	name := pd.Name
+	if len(pd.TypeParams) > 0 {
+		params := make([]string, 0, len(pd.TypeParams))
+		for _, tp := range pd.TypeParams {
+			params = append(params, tp.Names...)
+		}
+		name += "<" + strings.Join(params, ", ") + ">"
+	}
+
+	code, isInterface, err := g.declarationBody(pd, f)
+	if err != nil {
+		return err
+	}
+	if isInterface {
+		sb.WriteString("export interface " + name + " " + code + "\n")
+	} else {
+		sb.WriteString("export type " + name + " = " + code + ";\n")
+	}
+
+	f.Declarations = append(f.Declarations, Declaration{
+		Name: pd.Name,
+		Code: sb.String(),
+	})
+
+	// Done
+	return nil
+}
+
+// declarationBody returns the TypeScript type of a declaration and if it must be declared as an
+// interface
+func (g *generator) declarationBody(pd *parser.ParsedDeclaration, f *File) (string, bool, error) {
+	// Types with custom marshallers are not described by their fields
+	if len(pd.TypeParams) == 0 {
+		switch codegen.Marshaler(&parser.ParsedNonNativeType{Name: pd.Name, Ref: pd}) {
+		case "MarshalJSON":
+			return "unknown", false, nil
+		case "MarshalText":
+			return "string", false, nil
+		}
+
+		if g.opts.Packages != nil {
+			if literals := enumLiterals(g.opts.Packages.EnumValues(pd)); len(literals) > 0 {
+				return strings.Join(literals, " | "), false, nil
+			}
+		}
+	}
+
+	if ps, ok := parser.Unalias(pd.Type).(*parser.ParsedStruct); ok {
+		code, err := g.structType(ps, f, "")
+		return code, true, err
+	}
+	code, err := g.typeExpr(pd.Type, f, "")
+	return code, false, err
+}
+
+func (g *generator) typeExpr(t parser.ParsedType, f *File, indent string) (string, error) {
+	switch tType := t.(type) {
+	case *parser.ParsedNativeType:
+		return nativeType(tType.Name)
+
+	case *parser.ParsedNonNativeType:
+		id := codegen.ID(tType)
+		if ts, ok := wellKnownTypes[id]; ok {
+			return ts, nil
+		}
+		if tType.Ref == nil {
+			if id == "any" || id == "error" {
+				return "unknown", nil
+			}
+			return "", fmt.Errorf("unresolved reference to %s", tType.Name)
+		}
+
+		pd := tType.Ref
+		if pd.IsAlias {
+			return g.typeExpr(pd.Type, f, indent)
+		}
+		if len(pd.TypeParams) > 0 {
+			return "", fmt.Errorf("generic type %s must be instantiated", pd.Name)
+		}
+		return g.reference(pd, f)
+
+	case *parser.ParsedIndex:
+		generic, ok := tType.Type.(*parser.ParsedNonNativeType)
+		if !ok || generic.Ref == nil {
+			return "", fmt.Errorf("unresolved generic type %s", parser.Render(tType, nil))
+		}
+		name, err := g.reference(generic.Ref, f)
+		if err != nil {
+			return "", err
+		}
+		args := make([]string, 0, len(tType.Indexes))
+		for _, index := range tType.Indexes {
+			arg, err2 := g.typeExpr(index, f, indent)
+			if err2 != nil {
+				return "", err2
+			}
+			args = append(args, arg)
+		}
+		return name + "<" + strings.Join(args, ", ") + ">", nil
+
+	case *parser.ParsedTypeParamRef:
+		return tType.Name, nil
+
+	case *parser.ParsedStruct:
+		return g.structType(tType, f, indent)
+
+	case *parser.ParsedInterface:
+		return "unknown", nil
+
+	case *parser.ParsedMap:
+		key := "string"
+		if parser.IsInteger(tType.KeyType) {
+			key = "number"
+		} else if parser.IsString(tType.KeyType) {
+			var err error
+			key, err = g.typeExpr(tType.KeyType, f, indent)
+			if err != nil {
+				return "", err
+			}
+		}
+		value, err := g.typeExpr(tType.ValueType, f, indent)
+		if err != nil {
+			return "", err
+		}
+		// Nil maps are encoded as null and maps keyed by an enum do not need to have all the keys
+		if g.isEnum(tType.KeyType) {
+			return "Partial<Record<" + key + ", " + value + ">> | null", nil
+		}
+		return "Record<" + key + ", " + value + "> | null", nil
+
+	case *parser.ParsedArray:
+		nullable := ""
+		if len(tType.Size) == 0 {
+			nullable = " | null" // Nil slices are encoded as null
+			if codegen.IsByte(tType.ValueType) {
+				return "string" + nullable, nil // Byte slices are encoded as base64 strings
+			}
+		}
+		item, err := g.typeExpr(tType.ValueType, f, indent)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(item, " | ") {
+			item = "(" + item + ")"
+		}
+		return item + "[]" + nullable, nil
+
+	case *parser.ParsedPointer:
+		s, err := g.typeExpr(tType.ToType, f, indent)
+		if err != nil {
+			return "", err
+		}
+		if s == "unknown" || strings.HasSuffix(s, " | null") {
+			return s, nil
+		}
+		return s + " | null", nil
+	}
+
+	return "", fmt.Errorf("type %s cannot be represented in JSON", parser.Render(t, nil))
+}
+
+// isEnum returns true if the type is declared as a union of string literals
+func (g *generator) isEnum(t parser.ParsedType) bool {
+	pnnt, ok := parser.Unalias(t).(*parser.ParsedNonNativeType)
+	if !ok || pnnt.Ref == nil || g.opts.Packages == nil || len(pnnt.Ref.TypeParams) > 0 {
+		return false
+	}
+	return len(enumLiterals(g.opts.Packages.EnumValues(pnnt.Ref))) > 0
+}
+
+// reference queues a declaration and returns its name, qualified with the namespace of its file if
+// it belongs to another package
+func (g *generator) reference(pd *parser.ParsedDeclaration, f *File) (string, error) {
+	target, err := g.enqueue(pd)
+	if err != nil {
+		return "", err
+	}
+	if target == f {
+		return pd.Name, nil
+	}
+	return f.importAlias(target) + "." + pd.Name, nil
+}
+
+func (g *generator) structType(ps *parser.ParsedStruct, f *File, indent string) (string, error) {
+	fields := ps.JSONFields()
+	if len(fields) == 0 {
+		return "{}", nil
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("{\n")
+	for _, jf := range fields {
+		var ts string
+		var err error
+
+		if jf.AsString && codegen.IsStringable(jf.Field.Type) {
+			ts = "string"
+		} else {
+			ts, err = g.typeExpr(jf.Field.Type, f, indent+"  ")
+			if err != nil {
+				return "", fmt.Errorf("unable to convert field %s [err=%v]", jf.Name, err)
+			}
+		}
+
+		if jf.OmitEmpty && isCollection(jf.Field.Type) {
+			ts = strings.TrimSuffix(ts, " | null") // Nil slices and maps are omitted
+		}
+
+		writeDoc(&sb, jf.Field.Doc, indent+"  ")
+		sb.WriteString(indent + "  " + propertyName(jf.Name))
+		if jf.OmitEmpty {
+			sb.WriteString("?")
+		}
+		sb.WriteString(": " + ts + ";\n")
+	}
+	sb.WriteString(indent + "}")
+
+	// Done
+	return sb.String(), nil
+}
+
+// importAlias returns the namespace used to reference the declarations of another file, adding
+// the import if needed
+func (f *File) importAlias(target *File) string {
+	for _, imp := range f.Imports {
+		if imp.importPath == target.ImportPath {
+			return imp.Alias
+		}
+	}
+
+	alias := target.Package
+	for idx := 2; ; idx++ {
+		used := false
+		for _, imp := range f.Imports {
+			if imp.Alias == alias {
+				used = true
+				break
+			}
+		}
+		if !used {
+			break
+		}
+		alias = fmt.Sprintf("%s%d", target.Package, idx)
+	}
+
+	f.Imports = append(f.Imports, Import{
+		Alias:      alias,
+		From:       relativeImport(f.Filename, target.Filename),
+		importPath: target.ImportPath,
+	})
+	return alias
+}
+
+// -----------------------------------------------------------------------------
+
+func nativeType(name string) (string, error) {
+	switch name {
+	case "bool":
+		return "boolean", nil
+	case "string":
+		return "string", nil
+	case "int", "int8", "int16", "int32", "int64", "rune", "uint", "uint8", "uint16", "uint32", "uint64",
+		"uintptr", "byte", "float32", "float64":
+		return "number", nil
+	}
+	return "", fmt.Errorf("type %s cannot be represented in JSON", name)
+}
+
+// enumLiterals converts evaluated constants to TypeScript literals. Constants that were not
+// evaluated are skipped.
+func enumLiterals(constants []*parser.ParsedConstant) []string {
+	literals := make([]string, 0, len(constants))
+	seen := make(map[string]struct{})
+	for _, pc := range constants {
+		if pc.ParsedValue == nil {
+			continue
+		}
+
+		literal := ""
+		switch pc.ParsedValue.Kind() {
+		case constant.Bool:
+			literal = pc.ParsedValue.ExactString()
+		case constant.String:
+			literal = quote(constant.StringVal(pc.ParsedValue))
+		case constant.Int:
+			literal = pc.ParsedValue.ExactString()
+		case constant.Float:
+			v, _ := constant.Float64Val(pc.ParsedValue)
+			data, _ := json.Marshal(v)
+			literal = string(data)
+		default:
+			continue
+		}
+		if _, ok := seen[literal]; !ok {
+			seen[literal] = struct{}{}
+			literals = append(literals, literal)
+		}
+	}
+	return literals
+}
+
+// relativeImport returns the module specifier used to import a .d.ts file from another one
+func relativeImport(from string, to string) string {
+	rel := codegen.RelativePath(from, strings.TrimSuffix(to, ".d.ts"))
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// isCollection returns true for slices and maps, which are encoded as null when they are nil
+func isCollection(t parser.ParsedType) bool {
+	switch tType := parser.Unalias(t).(type) {
+	case *parser.ParsedArray:
+		return len(tType.Size) == 0
+	case *parser.ParsedMap:
+		return true
+	}
+	return false
+}
+
+// propertyName quotes property names that are not valid identifiers
+func propertyName(name string) string {
+	for idx, ch := range name {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch == '_', ch == '$':
+		case ch >= '0' && ch <= '9' && idx > 0:
+		default:
+			return quote(name)
+		}
+	}
+	if len(name) == 0 {
+		return quote(name)
+	}
+	return name
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func writeDoc(sb *strings.Builder, doc string, indent string) {
+	if len(doc) == 0 {
+		return
+	}
+	lines := strings.Split(strings.ReplaceAll(doc, "*/", "* /"), "\n")
+	if len(lines) == 1 {
+		sb.WriteString(indent + "/** " + lines[0] + " */\n")
+		return
+	}
+	sb.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		sb.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	sb.WriteString(indent + " */\n")
+}
@@ -0,0 +1,295 @@
+package typescript_test
+
+import (
+	"testing"
+
+	parser "github.com/mxmauro/gofile-parser"
+	"github.com/mxmauro/gofile-parser/typescript"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerate(t *testing.T) {
+	common, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package common
+
+type Money struct {
+	Amount   int64  ` + "`json:\"amount,string\"`" + `
+	Currency string ` + "`json:\"currency\"`" + `
+}
+`,
+		Filename: "common.go",
+		Module: parser.Module{
+			Name:   "github.com/mxmauro/gofile-parser-test",
+			SubDir: "common",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package api
+
+import (
+	"time"
+
+	"github.com/mxmauro/gofile-parser-test/common"
+)
+
+type Status string
+
+const (
+	Pending Status = "pending"
+	Shipped Status = "shipped"
+)
+
+type Base struct {
+	ID      string    ` + "`json:\"id\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+}
+
+// Order is a purchase order
+type Order struct {
+	Base
+	Status Status            ` + "`json:\"status\"`" + `
+	Total  common.Money      ` + "`json:\"total\"`" + `
+	Lines  Page[*Line]       ` + "`json:\"lines\"`" + `
+	Labels map[Status]string ` + "`json:\"labels,omitempty\"`" + `
+	Counts map[string]int    ` + "`json:\"counts\"`" + `
+	Note   *string           ` + "`json:\"note,omitempty\"`" + ` // Optional note
+	Raw    []byte            ` + "`json:\"x-raw\"`" + `
+	Secret string            ` + "`json:\"-\"`" + `
+}
+
+type Page[T any] struct {
+	Items []T ` + "`json:\"items\"`" + `
+	Next  *string
+}
+
+type Line struct {
+	SKU string
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	ps := parser.NewPackageSet([]*parser.ParsedFile{common, pf})
+	ps.ResolveReferences()
+
+	order := ps.Lookup("github.com/mxmauro/gofile-parser-test", "Order")
+	files, err := typescript.Generate([]*parser.ParsedDeclaration{order}, typescript.Options{
+		Packages: ps,
+	})
+	if err != nil {
+		t.Fatalf("unable to generate declarations [err=%v]", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("wrong number of files")
+	}
+
+	if files[0].Filename != "github.com/mxmauro/gofile-parser-test/api.d.ts" {
+		t.Fatalf("wrong filename %v", files[0].Filename)
+	}
+	expected := `import type * as common from "./common/common";
+
+/** Order is a purchase order */
+export interface Order {
+  id: string;
+  created: string;
+  status: Status;
+  total: common.Money;
+  lines: Page<Line | null>;
+  labels?: Partial<Record<Status, string>>;
+  counts: Record<string, number> | null;
+  /** Optional note */
+  note?: string | null;
+  "x-raw": string | null;
+}
+
+export type Status = "pending" | "shipped";
+
+export interface Page<T> {
+  items: T[] | null;
+  Next: string | null;
+}
+
+export interface Line {
+  SKU: string;
+}
+`
+	if files[0].String() != expected {
+		t.Fatalf("wrong declarations:\n%v", files[0].String())
+	}
+
+	expected = `export interface Money {
+  amount: string;
+  currency: string;
+}
+`
+	if files[1].String() != expected {
+		t.Fatalf("wrong declarations:\n%v", files[1].String())
+	}
+}
+
+func TestUnsupportedTypes(t *testing.T) {
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package main
+
+type Job struct {
+	Done chan bool ` + "`json:\"-\"`" + `
+	Name string
+	Next *Job
+}
+
+type Point struct {
+	Value complex128 ` + "`json:\"value\"`" + `
+}
+
+type Page[T any] struct {
+	Items []T
+}
+
+type List struct {
+	Page Page[Item] ` + "`json:\"page\"`" + `
+}
+
+type Task struct {
+	Owner *Account ` + "`json:\"owner\"`" + `
+}
+`,
+		Filename: "test.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	parser.ResolveReferences([]*parser.ParsedFile{pf})
+
+	// Fields excluded with the json tag are not converted
+	files, err := typescript.Generate([]*parser.ParsedDeclaration{&pf.Declarations[0]}, typescript.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate files [err=%v]", err)
+	}
+	expected := `export interface Job {
+  Name: string;
+  Next: Job | null;
+}
+`
+	if files[0].String() != expected {
+		t.Fatalf("wrong file:\n%v", files[0].String())
+	}
+
+	// Declarations are stored in the file of their package, so they must belong to a parsed file
+	orphan := &parser.ParsedDeclaration{
+		Name: "Account",
+		Type: &parser.ParsedStruct{},
+	}
+	owner := pf.Declarations[4].Type.(*parser.ParsedStruct).Fields[0].Type.(*parser.ParsedPointer)
+	owner.ToType.(*parser.ParsedNonNativeType).Ref = orphan
+
+	for _, tc := range []struct {
+		pd       *parser.ParsedDeclaration
+		expected string
+	}{
+		{&pf.Declarations[1], "unable to convert field value [err=type complex128 cannot be represented in JSON]"},
+		{&pf.Declarations[3], "unable to convert field page [err=unresolved reference to Item]"},
+		{&pf.Declarations[4], "unable to convert field owner [err=declaration Account does not belong to a parsed file]"},
+	} {
+		_, err = typescript.Generate([]*parser.ParsedDeclaration{tc.pd}, typescript.Options{})
+		if err == nil || err.Error() != "unable to convert "+tc.pd.Name+" [err="+tc.expected+"]" {
+			t.Fatalf("wrong error for %v: %v", tc.pd.Name, err)
+		}
+	}
+
+	_, err = typescript.Generate([]*parser.ParsedDeclaration{orphan}, typescript.Options{})
+	if err == nil || err.Error() != "declaration Account does not belong to a parsed file" {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestImportAliases(t *testing.T) {
+	files := make([]*parser.ParsedFile, 0)
+	for _, subDir := range []string{"a/models", "b/models"} {
+		pf, err := parser.ParseText(parser.ParseTextOptions{
+			Content: `
+package models
+
+type User struct {
+	Name string
+}
+`,
+			Filename: "models.go",
+			Module: parser.Module{
+				Name:   "github.com/mxmauro/gofile-parser-test",
+				SubDir: subDir,
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		files = append(files, pf)
+	}
+
+	pf, err := parser.ParseText(parser.ParseTextOptions{
+		Content: `
+package api
+
+import (
+	"github.com/mxmauro/gofile-parser-test/a/models"
+	other "github.com/mxmauro/gofile-parser-test/b/models"
+)
+
+type User struct {
+	Local  models.User
+	Remote other.User
+	Parent *User
+}
+`,
+		Filename: "api.go",
+		Module: parser.Module{
+			Name: "github.com/mxmauro/gofile-parser-test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	files = append(files, pf)
+
+	ps := parser.NewPackageSet(files)
+	ps.ResolveReferences()
+
+	result, err := typescript.Generate([]*parser.ParsedDeclaration{
+		ps.Lookup("github.com/mxmauro/gofile-parser-test", "User"),
+	}, typescript.Options{})
+	if err != nil {
+		t.Fatalf("unable to generate declarations [err=%v]", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("wrong number of files")
+	}
+
+	expected := `import type * as models from "./a/models/models";
+import type * as models2 from "./b/models/models";
+
+export interface User {
+  Local: models.User;
+  Remote: models2.User;
+  Parent: User | null;
+}
+`
+	if result[0].String() != expected {
+		t.Fatalf("wrong declarations:\n%v", result[0].String())
+	}
+}
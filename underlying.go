@@ -0,0 +1,165 @@
+package parser
+
+// -----------------------------------------------------------------------------
+
+// Underlying follows resolved references, including generic instantiations, and returns the node
+// at the end of the chain. For example, given `type ID UserID` and `type UserID string`, the
+// underlying type of ID is the ParsedNativeType of string.
+//
+// If a reference is not resolved, the ParsedNonNativeType is returned as is. Nil is returned if
+// the chain contains a cycle or an instantiation fails.
+func Underlying(t ParsedType) ParsedType {
+	visited := make(map[*ParsedDeclaration]struct{})
+
+	for {
+		switch tType := t.(type) {
+		case *ParsedNonNativeType:
+			if tType.Ref == nil {
+				return t
+			}
+			if _, ok := visited[tType.Ref]; ok {
+				return nil // Cycle detected
+			}
+			visited[tType.Ref] = struct{}{}
+			t = tType.Ref.Type
+
+		case *ParsedIndex:
+			pd, err := Instantiate(tType)
+			if err != nil {
+				return nil
+			}
+			t = pd.Type
+
+		default:
+			return t
+		}
+	}
+}
+
+// Unalias follows references to alias declarations, like `type A = B`, and returns the first
+// node that is not an alias.
+//
+// Nil is returned if the chain contains a cycle.
+func Unalias(t ParsedType) ParsedType {
+	visited := make(map[*ParsedDeclaration]struct{})
+
+	for {
+		pnnt, ok := t.(*ParsedNonNativeType)
+		if !ok || pnnt.Ref == nil || !pnnt.Ref.IsAlias {
+			return t
+		}
+		if _, ok = visited[pnnt.Ref]; ok {
+			return nil // Cycle detected
+		}
+		visited[pnnt.Ref] = struct{}{}
+		t = pnnt.Ref.Type
+	}
+}
+
+func (pnnt *ParsedNonNativeType) Underlying() ParsedType {
+	return Underlying(pnnt)
+}
+
+func (pnnt *ParsedNonNativeType) Unalias() ParsedType {
+	return Unalias(pnnt)
+}
+
+func (pd *ParsedDeclaration) Underlying() ParsedType {
+	return Underlying(&ParsedNonNativeType{
+		Name: pd.Name,
+		Ref:  pd,
+	})
+}
+
+func IsBool(t ParsedType) bool {
+	return underlyingNativeName(t) == "bool"
+}
+
+func IsString(t ParsedType) bool {
+	return underlyingNativeName(t) == "string"
+}
+
+func IsInteger(t ParsedType) bool {
+	switch underlyingNativeName(t) {
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return true
+	}
+	return IsUnsigned(t)
+}
+
+func IsUnsigned(t ParsedType) bool {
+	switch underlyingNativeName(t) {
+	case "uint", "uint8", "uint16", "uint32", "uint64", "byte", "uintptr":
+		return true
+	}
+	return false
+}
+
+func IsFloat(t ParsedType) bool {
+	switch underlyingNativeName(t) {
+	case "float", "float32", "float64":
+		return true
+	}
+	return false
+}
+
+func IsComplex(t ParsedType) bool {
+	switch underlyingNativeName(t) {
+	case "complex64", "complex128":
+		return true
+	}
+	return false
+}
+
+func IsNumeric(t ParsedType) bool {
+	return IsInteger(t) || IsFloat(t) || IsComplex(t)
+}
+
+func IsStruct(t ParsedType) bool {
+	_, ok := Underlying(t).(*ParsedStruct)
+	return ok
+}
+
+func IsInterface(t ParsedType) bool {
+	_, ok := Underlying(t).(*ParsedInterface)
+	return ok
+}
+
+func IsMap(t ParsedType) bool {
+	_, ok := Underlying(t).(*ParsedMap)
+	return ok
+}
+
+func IsSlice(t ParsedType) bool {
+	pa, ok := Underlying(t).(*ParsedArray)
+	return ok && len(pa.Size) == 0
+}
+
+func IsArray(t ParsedType) bool {
+	pa, ok := Underlying(t).(*ParsedArray)
+	return ok && len(pa.Size) > 0
+}
+
+func IsPointer(t ParsedType) bool {
+	_, ok := Underlying(t).(*ParsedPointer)
+	return ok
+}
+
+func IsChannel(t ParsedType) bool {
+	_, ok := Underlying(t).(*ParsedChannel)
+	return ok
+}
+
+func IsFunction(t ParsedType) bool {
+	_, ok := Underlying(t).(*ParsedFunction)
+	return ok
+}
+
+// -----------------------------------------------------------------------------
+
+func underlyingNativeName(t ParsedType) string {
+	if pnt, ok := Underlying(t).(*ParsedNativeType); ok {
+		return pnt.Name
+	}
+	return ""
+}
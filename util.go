@@ -17,7 +17,7 @@ func IsPublic(name string) bool {
 
 func IsNativeType(name string) bool {
 	switch name {
-	case "bool", "byte", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "string", "float", "float64", "float32", "complex128", "complex64", "uintptr":
+	case "bool", "byte", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "string", "float", "float64", "float32", "complex128", "complex64", "uintptr", "rune":
 		return true
 	}
 	return false
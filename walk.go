@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// Path describes how a node was reached while walking the model. It contains the visited
+// ancestors, starting at the root, followed by the node itself.
+type Path []interface{}
+
+// WalkFunc is called for each node. If it returns true, the children of the node are visited
+// and, once done, the function is called again with a nil node.
+type WalkFunc func(node interface{}, path Path) bool
+
+// Visitor is the interface-based version of WalkFunc. The Visit method is called for each node.
+// If the returned visitor is not nil, it is used to visit the children of the node and, once
+// done, its Visit method is called again with a nil node.
+type Visitor interface {
+	Visit(node interface{}, path Path) (w Visitor)
+}
+
+type walker struct {
+	followRefs bool
+}
+
+type inspector WalkFunc
+
+// -----------------------------------------------------------------------------
+
+// Walk traverses the model in depth-first order, starting at the given node. The node can be a
+// *ParsedFile, *ParsedDeclaration, *ParsedConstant, *ParsedMethod, *ParsedField or any ParsedType. Children
+// include struct fields, interface members, function type parameters, parameters and results,
+// and generic indexes. References of ParsedNonNativeType nodes are not followed.
+func Walk(node interface{}, fn WalkFunc) {
+	w := walker{}
+	w.walk(inspector(fn), node, nil)
+}
+
+// WalkReferences works like Walk but it also visits the declaration referenced by each resolved
+// ParsedNonNativeType node. A declaration already present in the current path is not visited
+// again, so recursive types do not loop forever.
+func WalkReferences(node interface{}, fn WalkFunc) {
+	w := walker{
+		followRefs: true,
+	}
+	w.walk(inspector(fn), node, nil)
+}
+
+// WalkVisitor traverses the model like Walk does but using a Visitor
+func WalkVisitor(v Visitor, node interface{}) {
+	w := walker{}
+	w.walk(v, node, nil)
+}
+
+// String returns the dotted path of names leading to the node, for example, User.Address.Street.
+// Declarations reached through references are omitted because the field name is already present.
+func (p Path) String() string {
+	names := make([]string, 0, len(p))
+	for idx, node := range p {
+		switch n := node.(type) {
+		case *ParsedDeclaration:
+			if idx == 0 {
+				names = append(names, n.Name)
+			}
+		case *ParsedConstant:
+			names = append(names, n.Name)
+		case *ParsedMethod:
+			names = append(names, n.Receiver, n.Name)
+		case *ParsedField:
+			if ids := n.Identifiers(); len(ids) > 0 {
+				names = append(names, strings.Join(ids, ","))
+			}
+		}
+	}
+	return strings.Join(names, ".")
+}
+
+func (f inspector) Visit(node interface{}, path Path) Visitor {
+	if f(node, path) {
+		return f
+	}
+	return nil
+}
+
+func (w *walker) walk(v Visitor, node interface{}, path Path) {
+	if node == nil {
+		return
+	}
+
+	// Force a copy on append so paths given to visitors are never modified
+	path = append(path[:len(path):len(path)], node)
+
+	if v = v.Visit(node, path); v == nil {
+		return
+	}
+
+	walkFields := func(fields []ParsedField) {
+		for idx := range fields {
+			w.walk(v, &fields[idx], path)
+		}
+	}
+	walkType := func(t ParsedType) {
+		if t != nil {
+			w.walk(v, t, path)
+		}
+	}
+
+	switch n := node.(type) {
+	case *ParsedFile:
+		for idx := range n.Declarations {
+			w.walk(v, &n.Declarations[idx], path)
+		}
+		for idx := range n.Constants {
+			w.walk(v, &n.Constants[idx], path)
+		}
+		for idx := range n.Methods {
+			w.walk(v, &n.Methods[idx], path)
+		}
+
+	case *ParsedDeclaration:
+		walkFields(n.TypeParams)
+		walkType(n.Type)
+
+	case *ParsedConstant:
+		walkType(n.Type)
+
+	case *ParsedMethod:
+		if n.Type != nil {
+			w.walk(v, n.Type, path)
+		}
+
+	case *ParsedField:
+		walkType(n.Type)
+
+	case *ParsedNonNativeType:
+		if w.followRefs && n.Ref != nil && !path.contains(n.Ref) {
+			w.walk(v, n.Ref, path)
+		}
+
+	case *ParsedStruct:
+		walkFields(n.Fields)
+
+	case *ParsedInterface:
+		walkFields(n.Methods)
+
+	case *ParsedMap:
+		walkType(n.KeyType)
+		walkType(n.ValueType)
+
+	case *ParsedArray:
+		walkType(n.ValueType)
+
+	case *ParsedPointer:
+		walkType(n.ToType)
+
+	case *ParsedChannel:
+		walkType(n.Type)
+
+	case *ParsedFunction:
+		walkFields(n.TypeParams)
+		walkFields(n.Params)
+		walkFields(n.Results)
+
+	case *ParsedIndex:
+		walkType(n.Type)
+		for _, idx := range n.Indexes {
+			walkType(idx)
+		}
+
+	case *ParsedUnion:
+		for _, term := range n.Terms {
+			walkType(term.Type)
+		}
+	}
+
+	v.Visit(nil, path)
+}
+
+func (p Path) contains(node interface{}) bool {
+	for _, n := range p {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}